@@ -0,0 +1,113 @@
+// Copyright 2018 The SS.SYSU Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rxgo
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// default max time an item waits in a batch if the latency of WithBatching is not positive
+var DefaultBatchLatency = time.Millisecond
+
+// WithBatching sends items between stages in batches of at most size items. An item waits in a batch
+// no longer than latency before the batch is sent. Batching is disabled if size is less than 2.
+// Len of StageStats counts batches waiting in the channel.
+func WithBatching(size int, latency time.Duration) Option {
+	return func(op *Options) {
+		op.BatchSize = size
+		op.BatchLatency = latency
+	}
+}
+
+// a batch of items sent to the channel of a flow at once
+type itemBatch []interface{}
+
+// batcher collects items sent to a flow and sends them to the channel in batches.
+// The lock is held while sending, so batches keep the order of items.
+type batcher struct {
+	mu      sync.Mutex
+	ch      chan interface{}
+	ctx     context.Context // context of the sender stage
+	size    int
+	latency time.Duration
+	items   itemBatch
+	timer   *time.Timer
+	closed  bool
+}
+
+// create batcher of the flow from stage o if batching is enabled, or returns nil
+func (o *Observable) newBatcher(ctx context.Context, ch chan interface{}) *batcher {
+	op := optionsOf(ctx)
+	if op == nil || op.BatchSize < 2 {
+		return nil
+	}
+	b := &batcher{ch: ch, ctx: ctx, size: op.BatchSize, latency: op.BatchLatency}
+	if b.latency <= 0 {
+		b.latency = DefaultBatchLatency
+	}
+	return b
+}
+
+// add an item to the batch and send the batch if it is full. It returns true if ctx is done
+func (b *batcher) add(ctx context.Context, x interface{}) (end bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.items == nil {
+		b.items = make(itemBatch, 0, b.size)
+	}
+	b.items = append(b.items, x)
+	if len(b.items) >= b.size {
+		return b.flush(ctx)
+	}
+	if len(b.items) == 1 {
+		if b.timer == nil {
+			b.timer = time.AfterFunc(b.latency, b.expire)
+		} else {
+			b.timer.Reset(b.latency)
+		}
+	}
+	return false
+}
+
+// send items waiting in the batch, b.mu must be held
+func (b *batcher) flush(ctx context.Context) (end bool) {
+	if len(b.items) == 0 {
+		return false
+	}
+	items := b.items
+	b.items = nil
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+	select {
+	case b.ch <- items:
+	case <-ctx.Done():
+		end = true
+	}
+	return
+}
+
+// send the batch when the first item in it waits too long
+func (b *batcher) expire() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.closed {
+		b.flush(b.ctx)
+	}
+}
+
+// send items left in the batch and close the channel
+func (b *batcher) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.flush(b.ctx)
+	b.closed = true
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+	close(b.ch)
+}
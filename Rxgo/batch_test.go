@@ -0,0 +1,94 @@
+package rxgo_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pmlpml/rxgo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatching(t *testing.T) {
+	expected := []int{}
+	for i := 0; i < 1000; i++ {
+		if i%3 != 0 {
+			expected = append(expected, 2*i)
+		}
+	}
+
+	for _, th := range []rxgo.ThreadModel{rxgo.ThreadingDefault, rxgo.ThreadingIO} {
+		res := []int{}
+		ob := rxgo.Range(0, 1000).Filter(func(x int) bool {
+			return x%3 != 0
+		}).Map(func(x int) int {
+			return 2 * x
+		}).Configure(rxgo.WithBatching(32, time.Millisecond), rxgo.WithThreading(th))
+		ob.Subscribe(func(x int) {
+			res = append(res, x)
+		})
+		if th == rxgo.ThreadingDefault {
+			assert.Equal(t, expected, res, "batched items error")
+		} else {
+			assert.Equal(t, len(expected), len(res), "batched items count error")
+		}
+		assert.Equal(t, uint64(len(expected)), ob.Stats()[2].Processed, "processed items error")
+	}
+}
+
+func TestBatchingLatency(t *testing.T) {
+	ch := make(chan interface{})
+	received := make(chan int)
+	s := rxgo.From(ch).Map(func(x int) int {
+		return x
+	}).Configure(rxgo.WithBatching(100, 5*time.Millisecond)).SubscribeAsync(func(x int) {
+		received <- x
+	})
+
+	ch <- 1
+	select {
+	case x := <-received:
+		assert.Equal(t, 1, x, "received item error")
+	case <-time.After(time.Second):
+		t.Error("batch is not sent after latency")
+	}
+	close(ch)
+	s.Wait()
+}
+
+func TestBatchingWithCancel(t *testing.T) {
+	res := []int{}
+	var observer = rxgo.ObserverMonitor{}
+	observer.Next = func(y interface{}) {
+		x := y.(int)
+		res = append(res, x)
+		if x >= 3 {
+			observer.Unsubscribe()
+		}
+	}
+	observer.Context = func() context.Context {
+		ctx, cancel := context.WithCancel(context.Background())
+		observer.CancelObservables = cancel
+		return ctx
+	}
+
+	rxgo.Range(0, 100).Configure(rxgo.WithBatching(50, time.Millisecond)).Subscribe(observer)
+	assert.Equal(t, []int{0, 1, 2, 3}, res, "batch cancel error")
+}
+
+func BenchmarkBatching(b *testing.B) {
+	for _, size := range []int{0, 16, 128} {
+		b.Run(fmt.Sprintf("size=%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				ob := rxgo.Range(0, 10000)
+				for j := 0; j < 5; j++ {
+					ob = ob.Map(func(x int) int {
+						return x + 1
+					})
+				}
+				ob.Configure(rxgo.WithBatching(size, time.Millisecond)).Subscribe(func(x int) {})
+			}
+		})
+	}
+}
@@ -0,0 +1,445 @@
+// Copyright 2018 The SS.SYSU Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rxgo
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// RecordError is a malformed record of a decoder or encoder. It flows to subscriber as an error and the stream continues.
+type RecordError struct {
+	Line   int    // line number of the record from 1
+	Record string // the malformed line, or the item formatted by fmt.Print for encoders
+	Err    error
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// codecStage is the state of a codec in a subscription
+type codecStage interface {
+	next(x interface{}, send func(x interface{}) (end bool)) (end bool)
+	complete(send func(x interface{}) (end bool))
+}
+
+// codec node implementation of streamOperator. Items are processed one by one in order
+// by a codecStage created for each subscription. Errors flowed are sent to the next stage,
+// and a panic of the codecStage is sent as OperatorError.
+type codecOperator struct {
+	newStage func() codecStage
+}
+
+func (cop codecOperator) op(ctx context.Context, o *Observable, in, out *flow) {
+	cs := cop.newStage()
+	o.receive(ctx, in, out, &receiver{
+		next: func(x interface{}) (stop bool) {
+			ictx, x := recvItem(ctx, x)
+			if e, ok := x.(error); ok {
+				return o.sendToFlow(ictx, e, out)
+			}
+			ictx, finish := o.startSpan(ictx, x)
+			defer finish()
+			defer func() {
+				if e := recover(); e != nil {
+					stop = o.sendToFlow(ictx, o.operatorError(ictx, x, e), out)
+				}
+			}()
+			return cs.next(x, func(y interface{}) bool {
+				return o.sendToFlow(ictx, y, out)
+			})
+		},
+		complete: func() {
+			defer o.closeFlow(out)
+			defer func() {
+				if e := recover(); e != nil {
+					o.sendToFlow(ctx, o.operatorError(ctx, nil, e), out)
+				}
+			}()
+			cs.complete(func(y interface{}) bool {
+				return o.sendToFlow(ctx, y, out)
+			})
+		},
+	})
+}
+
+func (parent *Observable) newCodecObservable(name string, newStage func() codecStage) (o *Observable) {
+	o = parent.newTransformObservable(name)
+	o.operator = codecOperator{newStage}
+	return o
+}
+
+// text of a line item, string or []byte
+func lineText(x interface{}) (string, error) {
+	switch l := x.(type) {
+	case string:
+		return l, nil
+	case []byte:
+		return string(l), nil
+	}
+	return "", fmt.Errorf("rxgo: %T is not a line", x)
+}
+
+// DecodeJSONLines decodes lines of string or []byte, such as items of FromLines, into values of the type of proto.
+// A pointer is emitted if proto is a pointer, and maps, slices and so on are emitted if proto is nil.
+// Blank lines are skipped and malformed lines flow as RecordError.
+func (parent *Observable) DecodeJSONLines(proto interface{}) *Observable {
+	t := reflect.TypeOf(proto)
+	return parent.newCodecObservable("decodeJSONLines", func() codecStage {
+		return &jsonDecoder{t: t}
+	})
+}
+
+type jsonDecoder struct {
+	t    reflect.Type
+	line int
+}
+
+func (d *jsonDecoder) next(x interface{}, send func(x interface{}) (end bool)) (end bool) {
+	d.line++
+	text, e := lineText(x)
+	if e == nil && strings.TrimSpace(text) == "" {
+		return false
+	}
+	if e == nil {
+		var v reflect.Value
+		switch {
+		case d.t == nil:
+			v = reflect.New(typeAny)
+		case d.t.Kind() == reflect.Ptr:
+			v = reflect.New(d.t.Elem())
+		default:
+			v = reflect.New(d.t)
+		}
+		if e = json.Unmarshal([]byte(text), v.Interface()); e == nil {
+			if d.t == nil || d.t.Kind() != reflect.Ptr {
+				v = v.Elem()
+			}
+			return send(v.Interface())
+		}
+	}
+	return send(&RecordError{Line: d.line, Record: fmt.Sprint(x), Err: e})
+}
+
+func (d *jsonDecoder) complete(send func(x interface{}) (end bool)) {
+}
+
+// EncodeJSONLines encodes items into JSON lines of string without end-of-line markers, for ToWriter or ToFile.
+// Items can not be encoded flow as RecordError.
+func (parent *Observable) EncodeJSONLines() *Observable {
+	return parent.newCodecObservable("encodeJSONLines", func() codecStage {
+		return &jsonEncoder{}
+	})
+}
+
+type jsonEncoder struct {
+	line int
+}
+
+func (c *jsonEncoder) next(x interface{}, send func(x interface{}) (end bool)) (end bool) {
+	c.line++
+	b, e := json.Marshal(x)
+	if e != nil {
+		return send(&RecordError{Line: c.line, Record: fmt.Sprint(x), Err: e})
+	}
+	return send(string(b))
+}
+
+func (c *jsonEncoder) complete(send func(x interface{}) (end bool)) {
+}
+
+// CSVOptions of DecodeCSV
+type CSVOptions struct {
+	Comma   rune        // field delimiter, ',' if zero
+	Comment rune        // lines beginning with the comment character are skipped, no comment if zero
+	Header  bool        // the first line is names of columns
+	Proto   interface{} // struct or pointer to struct which records are decoded into, []string records if nil
+}
+
+// DecodeCSV decodes lines of string or []byte, such as items of FromLines, into records. A record is a []string,
+// or a map[string]string if opts.Header is set without opts.Proto, or a value of the type of opts.Proto
+// whose fields are matched to columns by `csv` tags or names ignoring case, or in order if no header.
+// Blank lines are skipped and malformed lines flow as RecordError. A quoted field can not contain newlines.
+// It panics with ErrFuncFlip if opts.Proto is not a struct or pointer to struct.
+func (parent *Observable) DecodeCSV(opts CSVOptions) *Observable {
+	if opts.Comma == 0 {
+		opts.Comma = ','
+	}
+	var cs *structFields
+	if opts.Proto != nil {
+		if cs = newStructFields(reflect.TypeOf(opts.Proto), "csv"); cs == nil {
+			panic(ErrFuncFlip)
+		}
+	}
+	return parent.newCodecObservable("decodeCSV", func() codecStage {
+		d := &csvDecoder{opts: opts}
+		if cs != nil {
+			d.cs = *cs
+		}
+		if !opts.Header && cs != nil {
+			d.cs.columns(nil)
+		}
+		return d
+	})
+}
+
+type csvDecoder struct {
+	opts   CSVOptions
+	cs     structFields
+	header []string
+	line   int
+}
+
+func (d *csvDecoder) next(x interface{}, send func(x interface{}) (end bool)) (end bool) {
+	d.line++
+	text, e := lineText(x)
+	if e == nil && strings.TrimSpace(text) == "" {
+		return false
+	}
+	var rec []string
+	if e == nil {
+		r := csv.NewReader(strings.NewReader(text))
+		r.Comma, r.Comment = d.opts.Comma, d.opts.Comment
+		if rec, e = r.Read(); e == io.EOF {
+			return false // comment
+		}
+	}
+	if e == nil && d.opts.Header && d.header == nil {
+		d.header = rec
+		if d.cs.t != nil {
+			d.cs.columns(rec)
+		}
+		return false
+	}
+	if e == nil && d.header != nil && len(rec) != len(d.header) {
+		e = csv.ErrFieldCount
+	}
+	if e == nil {
+		var v interface{}
+		if v, e = d.record(rec); e == nil {
+			return send(v)
+		}
+	}
+	return send(&RecordError{Line: d.line, Record: fmt.Sprint(x), Err: e})
+}
+
+// convert fields to the record
+func (d *csvDecoder) record(rec []string) (interface{}, error) {
+	switch {
+	case d.cs.t != nil:
+		return d.cs.decode(rec)
+	case d.header != nil:
+		m := make(map[string]string, len(rec))
+		for i, name := range d.header {
+			m[name] = rec[i]
+		}
+		return m, nil
+	}
+	return rec, nil
+}
+
+func (d *csvDecoder) complete(send func(x interface{}) (end bool)) {
+}
+
+// EncodeCSV encodes records into CSV lines of string without end-of-line markers, for ToWriter or ToFile.
+// A record is a []string, or a struct or pointer to struct whose fields are matched to header by `csv` tags
+// or names ignoring case, or all exported fields in order if header is nil. The header line is sent first if not nil.
+// Records can not be encoded flow as RecordError.
+func (parent *Observable) EncodeCSV(header []string) *Observable {
+	return parent.newCodecObservable("encodeCSV", func() codecStage {
+		return &csvEncoder{header: header, structs: make(map[reflect.Type]*structFields)}
+	})
+}
+
+type csvEncoder struct {
+	header  []string
+	structs map[reflect.Type]*structFields
+	line    int
+}
+
+func (c *csvEncoder) next(x interface{}, send func(x interface{}) (end bool)) (end bool) {
+	if c.line == 0 && c.header != nil {
+		c.line++
+		if end := send(csvLine(c.header)); end {
+			return true
+		}
+	}
+	c.line++
+	var rec []string
+	var e error
+	switch r := x.(type) {
+	case []string:
+		rec = r
+	default:
+		t := reflect.TypeOf(x)
+		cs, ok := c.structs[t]
+		if !ok {
+			cs = newStructFields(t, "csv")
+			if cs != nil {
+				cs.columns(c.header)
+			}
+			c.structs[t] = cs
+		}
+		if v := reflect.ValueOf(x); cs == nil || v.Kind() == reflect.Ptr && v.IsNil() {
+			e = fmt.Errorf("rxgo: %T(%v) is not a CSV record", x, x)
+		} else {
+			rec = cs.encode(reflect.ValueOf(x))
+		}
+	}
+	if e != nil {
+		return send(&RecordError{Line: c.line, Record: fmt.Sprint(x), Err: e})
+	}
+	return send(csvLine(rec))
+}
+
+func (c *csvEncoder) complete(send func(x interface{}) (end bool)) {
+	if c.line == 0 && c.header != nil {
+		send(csvLine(c.header))
+	}
+}
+
+// format a CSV line without end-of-line markers
+func csvLine(rec []string) string {
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	w.Write(rec)
+	w.Flush()
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// structFields maps columns to fields of a struct
+type structFields struct {
+	t      reflect.Type // struct type
+	ptr    bool         // records are pointers to t
+	tag    string       // key of field tags naming columns
+	fields []int        // index of field of each column, -1 if no field
+}
+
+// create structFields of struct type or pointer to struct type t, nil if t is not
+func newStructFields(t reflect.Type, tag string) *structFields {
+	if t == nil {
+		return nil
+	}
+	cs := &structFields{t: t, tag: tag}
+	if t.Kind() == reflect.Ptr {
+		cs.t, cs.ptr = t.Elem(), true
+	}
+	if cs.t.Kind() != reflect.Struct {
+		return nil
+	}
+	return cs
+}
+
+// match columns to fields by names, or all exported fields in order if names is nil. Fields tagged "-" are
+// skipped. A name matches the tag of a field first, then the tag or the name of a field case-insensitively.
+func (cs *structFields) columns(names []string) {
+	cs.fields = nil
+	tags := make([]string, cs.t.NumField())
+	for i := range tags {
+		f := cs.t.Field(i)
+		if f.PkgPath != "" {
+			tags[i] = "-"
+			continue
+		}
+		tags[i], _, _ = strings.Cut(f.Tag.Get(cs.tag), ",")
+		if names == nil && tags[i] != "-" {
+			cs.fields = append(cs.fields, i)
+		}
+	}
+	for _, name := range names {
+		index := -1
+		for i, tag := range tags {
+			if tag != "" && tag != "-" && tag == name {
+				index = i
+				break
+			}
+		}
+		for i, tag := range tags {
+			if tag == "" {
+				tag = cs.t.Field(i).Name
+			}
+			if index < 0 && name != "" && tag != "-" && strings.EqualFold(tag, name) {
+				index = i
+			}
+		}
+		cs.fields = append(cs.fields, index)
+	}
+}
+
+func (cs *structFields) decode(rec []string) (interface{}, error) {
+	v := reflect.New(cs.t)
+	for col, i := range cs.fields {
+		if i < 0 || col >= len(rec) {
+			continue
+		}
+		if e := setField(v.Elem().Field(i), rec[col]); e != nil {
+			return nil, fmt.Errorf("column %d: %w", col+1, e)
+		}
+	}
+	if cs.ptr {
+		return v.Interface(), nil
+	}
+	return v.Elem().Interface(), nil
+}
+
+func (cs *structFields) encode(v reflect.Value) []string {
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	rec := make([]string, len(cs.fields))
+	for col, i := range cs.fields {
+		if i >= 0 {
+			rec[col] = fmt.Sprint(v.Field(i).Interface())
+		}
+	}
+	return rec
+}
+
+// set a field of string, bool or number by text
+func setField(f reflect.Value, text string) error {
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(text)
+	case reflect.Bool:
+		b, e := strconv.ParseBool(text)
+		if e != nil {
+			return e
+		}
+		f.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, e := strconv.ParseInt(text, 10, f.Type().Bits())
+		if e != nil {
+			return e
+		}
+		f.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, e := strconv.ParseUint(text, 10, f.Type().Bits())
+		if e != nil {
+			return e
+		}
+		f.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, e := strconv.ParseFloat(text, f.Type().Bits())
+		if e != nil {
+			return e
+		}
+		f.SetFloat(n)
+	default:
+		return fmt.Errorf("rxgo: unsupported field type %v", f.Type())
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package rxgo_test
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/pmlpml/rxgo"
+	"github.com/stretchr/testify/assert"
+)
+
+type codecPoint struct {
+	Name string `json:"name" csv:"name"`
+	X    int    `json:"x" csv:"x"`
+}
+
+// collect items and errors
+func collectItems(res *[]interface{}) func(x interface{}, err error) {
+	return func(x interface{}, err error) {
+		if err != nil {
+			*res = append(*res, err)
+		} else {
+			*res = append(*res, x)
+		}
+	}
+}
+
+func TestJSONLines(t *testing.T) {
+	res := []interface{}{}
+	rxgo.Just(`{"name":"a","x":1}`, "", []byte(`{"name":"b","x":2}`), `{bad`, `{"name":"c","x":3}`).
+		DecodeJSONLines(&codecPoint{}).
+		Subscribe(collectItems(&res))
+
+	assert.Equal(t, 4, len(res), "decoded items error")
+	assert.Equal(t, &codecPoint{"a", 1}, res[0], "decoded item error")
+	assert.Equal(t, &codecPoint{"c", 3}, res[3], "stream continues after malformed line")
+	var re *rxgo.RecordError
+	if assert.True(t, errors.As(res[2].(error), &re), "malformed line error") {
+		assert.Equal(t, 4, re.Line, "line number error")
+		assert.Equal(t, "{bad", re.Record, "malformed record error")
+	}
+
+	lines := []string{}
+	rxgo.Just(codecPoint{"a", 1}, map[string]int{"y": 2}).EncodeJSONLines().Subscribe(func(x string) {
+		lines = append(lines, x)
+	})
+	assert.Equal(t, []string{`{"name":"a","x":1}`, `{"y":2}`}, lines, "encoded lines error")
+}
+
+func TestDecodeCSV(t *testing.T) {
+	res := []interface{}{}
+	rxgo.Just("x,name", "1,a", "# note", "2", "z,b", `3,"c,d"`).
+		DecodeCSV(rxgo.CSVOptions{Header: true, Comment: '#', Proto: codecPoint{}}).
+		Subscribe(collectItems(&res))
+
+	assert.Equal(t, 4, len(res), "decoded items error")
+	assert.Equal(t, codecPoint{"a", 1}, res[0], "decoded record error")
+	assert.Equal(t, codecPoint{"c,d", 3}, res[3], "quoted field error")
+	var re *rxgo.RecordError
+	if assert.True(t, errors.As(res[1].(error), &re), "field count error") {
+		assert.Equal(t, 4, re.Line, "line number error")
+	}
+	if assert.True(t, errors.As(res[2].(error), &re), "field parse error") {
+		assert.Equal(t, 5, re.Line, "line number error")
+		var ne *strconv.NumError
+		assert.True(t, errors.As(re, &ne), "wrapped error")
+	}
+
+	res = res[:0]
+	rxgo.Just("a;1").DecodeCSV(rxgo.CSVOptions{Comma: ';'}).Subscribe(collectItems(&res))
+	assert.Equal(t, []interface{}{[]string{"a", "1"}}, res, "string records error")
+
+	res = res[:0]
+	rxgo.Just("k,v", "a,1").DecodeCSV(rxgo.CSVOptions{Header: true}).Subscribe(collectItems(&res))
+	assert.Equal(t, []interface{}{map[string]string{"k": "a", "v": "1"}}, res, "map records error")
+
+	// an empty column matches no field, tags are matched before names, and fields tagged "-" are skipped
+	type tagged struct {
+		A    string
+		B    string `csv:"a"`
+		Skip string `csv:"-"`
+	}
+	res = res[:0]
+	rxgo.Just("x,,a,skip", "1,2,3,4").DecodeCSV(rxgo.CSVOptions{Header: true, Proto: tagged{}}).Subscribe(collectItems(&res))
+	assert.Equal(t, []interface{}{tagged{B: "3"}}, res, "tagged records error")
+}
+
+type panicJSON struct{}
+
+func (panicJSON) MarshalJSON() ([]byte, error) {
+	panic("bad marshaler")
+}
+
+func TestCodecPanic(t *testing.T) {
+	res := []interface{}{}
+	rxgo.Just(1, panicJSON{}, 2).EncodeJSONLines().Subscribe(collectItems(&res))
+	if assert.Equal(t, 3, len(res), "items after panic error") {
+		var oe *rxgo.OperatorError
+		if assert.True(t, errors.As(res[1].(error), &oe), "panic is not an OperatorError") {
+			assert.Equal(t, "bad marshaler", oe.Value, "panic value error")
+			assert.Equal(t, "encodeJSONLines", oe.Stage, "stage error")
+		}
+		assert.Equal(t, "2", res[2], "item after panic error")
+	}
+
+	defer func() {
+		assert.Equal(t, rxgo.ErrFuncFlip, recover(), "non-struct proto is accepted")
+	}()
+	rxgo.Just("1").DecodeCSV(rxgo.CSVOptions{Proto: 42})
+}
+
+func TestEncodeCSV(t *testing.T) {
+	lines := []interface{}{}
+	rxgo.Just(codecPoint{"a", 1}, &codecPoint{"b,c", 2}, []string{"3", "d"}, 4).
+		EncodeCSV([]string{"x", "name"}).
+		Subscribe(collectItems(&lines))
+
+	assert.Equal(t, []interface{}{"x,name", "1,a", `2,"b,c"`, "3,d"}, lines[:4], "encoded lines error")
+	var re *rxgo.RecordError
+	if assert.True(t, errors.As(lines[4].(error), &re), "not a record error") {
+		assert.Equal(t, 5, re.Line, "line number error")
+	}
+
+	lines = lines[:0]
+	rxgo.Just((*codecPoint)(nil), nil, codecPoint{"a", 1}).EncodeCSV(nil).Subscribe(collectItems(&lines))
+	if assert.Equal(t, 3, len(lines), "nil records error") {
+		assert.True(t, errors.As(lines[0].(error), &re), "nil pointer record error")
+		assert.True(t, errors.As(lines[1].(error), &re), "nil record error")
+		assert.Equal(t, "a,1", lines[2], "record after nil error")
+	}
+
+	lines = lines[:0]
+	rxgo.Empty().EncodeCSV([]string{"x"}).Subscribe(collectItems(&lines))
+	assert.Equal(t, []interface{}{"x"}, lines, "header of empty stream error")
+
+	// each subscription has its own state
+	ob := rxgo.Just(codecPoint{"a", 1}).EncodeCSV(nil)
+	for i := 0; i < 2; i++ {
+		lines = lines[:0]
+		ob.Subscribe(collectItems(&lines))
+		assert.Equal(t, []interface{}{"a,1"}, lines, "encoded lines error")
+	}
+}
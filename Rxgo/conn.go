@@ -0,0 +1,172 @@
+// Copyright 2018 The SS.SYSU Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rxgo
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// Framing splits a byte stream into frames and writes frames to a byte stream
+type Framing struct {
+	Split   bufio.SplitFunc                       // split function returning a frame as token
+	Write   func(w io.Writer, frame []byte) error // write a frame
+	MaxSize int                                   // max size of a frame to read, bufio.MaxScanTokenSize if not positive
+}
+
+// NewlineFraming frames are lines ended by "\n", and a "\r" before it is dropped on read
+var NewlineFraming = Framing{
+	Split: bufio.ScanLines,
+	Write: func(w io.Writer, frame []byte) error {
+		if _, e := w.Write(frame); e != nil {
+			return e
+		}
+		_, e := w.Write([]byte{'\n'})
+		return e
+	},
+}
+
+// LengthPrefixFraming frames are prefixed by their length in 4 bytes of big endian
+var LengthPrefixFraming = Framing{
+	Split: scanLengthPrefix,
+	Write: func(w io.Writer, frame []byte) error {
+		var n [4]byte
+		binary.BigEndian.PutUint32(n[:], uint32(len(frame)))
+		if _, e := w.Write(n[:]); e != nil {
+			return e
+		}
+		_, e := w.Write(frame)
+		return e
+	},
+}
+
+func scanLengthPrefix(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if len(data) >= 4 {
+		n := int(binary.BigEndian.Uint32(data))
+		if len(data) >= 4+n {
+			return 4 + n, data[4 : 4+n], nil
+		}
+	}
+	if atEOF && len(data) > 0 {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	return 0, nil, nil
+}
+
+// FromConn creates an Observable of []byte frames read from conn by framing until EOF, and a read error is sent
+// before completion. conn is not closed by it, so it can be written by ToConn in the same pipeline.
+// conn can be read only once, so the Observable is subscribed once.
+func FromConn(conn net.Conn, framing Framing) *Observable {
+	o := newGeneratorObservable("FromConn")
+
+	o.flip = func(ctx context.Context, out *flow) {
+		// interrupt the blocked read on unsubscription
+		defer watchDeadline(ctx, conn.SetReadDeadline)()
+
+		sc := bufio.NewScanner(conn)
+		sc.Split(framing.Split)
+		if framing.MaxSize > 0 {
+			sc.Buffer(nil, framing.MaxSize)
+		}
+		for sc.Scan() {
+			frame := append([]byte(nil), sc.Bytes()...)
+			if b := o.sendToFlow(ctx, frame, out); b {
+				return
+			}
+		}
+		if e := sc.Err(); e != nil && ctx.Err() == nil {
+			o.sendToFlow(ctx, e, out)
+		}
+	}
+	o.operator = flipSource
+	return o
+}
+
+// set a deadline in the past by setDeadline to interrupt blocked reads or writes of a conn when ctx is done.
+// The returned function clears the deadline if it is set, so the conn can be used again.
+func watchDeadline(ctx context.Context, setDeadline func(t time.Time) error) func() {
+	done := make(chan struct{})
+	interrupted := make(chan bool, 1)
+	go func() {
+		select {
+		case <-ctx.Done():
+			setDeadline(time.Unix(1, 0))
+			interrupted <- true
+		case <-done:
+			interrupted <- false
+		}
+	}()
+	return func() {
+		close(done)
+		if <-interrupted {
+			setDeadline(time.Time{})
+		}
+	}
+}
+
+// ToConn subscribes and writes items to conn as frames by framing. An item is a []byte, a string,
+// or formatted by fmt.Print. It returns when completed or on the first error flowed or returned by conn,
+// or ctx.Err() if ctx is done, also while blocked on writing to conn. conn is not closed by it.
+func (o *Observable) ToConn(ctx context.Context, conn net.Conn, framing Framing) error {
+	defer watchDeadline(ctx, conn.SetWriteDeadline)()
+	bw := bufio.NewWriter(conn)
+	return o.sink(ctx, func(x interface{}) error {
+		var frame []byte
+		switch f := x.(type) {
+		case []byte:
+			frame = f
+		case string:
+			frame = []byte(f)
+		default:
+			frame = []byte(fmt.Sprint(x))
+		}
+		e := framing.Write(bw, frame)
+		if e == nil {
+			e = bw.Flush()
+		}
+		if e != nil && ctx.Err() != nil {
+			// interrupted by the deadline
+			return ctx.Err()
+		}
+		return e
+	})
+}
+
+// ConnObservable is an Observable of frames read from a connection accepted by ListenAndEmit
+type ConnObservable struct {
+	*Observable
+	Conn net.Conn // accepted connection, it should be closed by subscribers
+}
+
+// ListenAndEmit creates an Observable of *ConnObservable for each connection accepted from l, and its frames are read
+// by framing. l is closed on completion or unsubscription, and an accept error other than closing l is sent before completion.
+func ListenAndEmit(l net.Listener, framing Framing) *Observable {
+	o := newGeneratorObservable("ListenAndEmit")
+
+	o.flip = func(ctx context.Context, out *flow) {
+		defer watchCloser(ctx, l)()
+		for {
+			c, e := l.Accept()
+			if e != nil {
+				if ctx.Err() == nil && !errors.Is(e, net.ErrClosed) {
+					o.sendToFlow(ctx, e, out)
+				}
+				return
+			}
+			if b := o.sendToFlow(ctx, &ConnObservable{Observable: FromConn(c, framing), Conn: c}, out); b {
+				c.Close()
+				return
+			}
+		}
+	}
+	o.operator = flipSource
+	return o
+}
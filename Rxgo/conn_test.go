@@ -0,0 +1,138 @@
+package rxgo_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pmlpml/rxgo"
+	"github.com/stretchr/testify/assert"
+)
+
+// echo frames of each connection in upper case
+func serveUpper(l net.Listener, framing rxgo.Framing) *rxgo.Subscription {
+	return rxgo.ListenAndEmit(l, framing).SubscribeAsync(func(c *rxgo.ConnObservable) {
+		go func() {
+			defer c.Conn.Close()
+			c.Map(func(b []byte) []byte { return bytes.ToUpper(b) }).ToConn(context.Background(), c.Conn, framing)
+		}()
+	})
+}
+
+func TestConnTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err, "listen error") {
+		return
+	}
+	sub := serveUpper(l, rxgo.NewlineFraming)
+	defer sub.Dispose()
+
+	for i := 0; i < 2; i++ {
+		c, err := net.Dial("tcp", l.Addr().String())
+		if !assert.NoError(t, err, "dial error") {
+			return
+		}
+		err = rxgo.Just("abc", []byte("de"), 12).ToConn(context.Background(), c, rxgo.NewlineFraming)
+		assert.NoError(t, err, "write error")
+		c.(*net.TCPConn).CloseWrite()
+
+		res := []string{}
+		rxgo.FromConn(c, rxgo.NewlineFraming).Subscribe(func(b []byte) {
+			res = append(res, string(b))
+		})
+		c.Close()
+		assert.Equal(t, "ABC,DE,12", strings.Join(res, ","), "echoed frames error")
+	}
+}
+
+func TestConnUnix(t *testing.T) {
+	l, err := net.Listen("unix", filepath.Join(t.TempDir(), "s.sock"))
+	if !assert.NoError(t, err, "listen error") {
+		return
+	}
+	sub := serveUpper(l, rxgo.LengthPrefixFraming)
+	defer sub.Dispose()
+
+	c, err := net.Dial("unix", l.Addr().String())
+	if !assert.NoError(t, err, "dial error") {
+		return
+	}
+	defer c.Close()
+	err = rxgo.Just("line\nwith break", "", "x").ToConn(context.Background(), c, rxgo.LengthPrefixFraming)
+	assert.NoError(t, err, "write error")
+	c.(*net.UnixConn).CloseWrite()
+
+	res := []string{}
+	rxgo.FromConn(c, rxgo.LengthPrefixFraming).Subscribe(func(b []byte) {
+		res = append(res, string(b))
+	})
+	assert.Equal(t, []string{"LINE\nWITH BREAK", "", "X"}, res, "echoed frames error")
+}
+
+func TestFromConnTruncated(t *testing.T) {
+	a, b := net.Pipe()
+	go func() {
+		b.Write([]byte{0, 0, 0, 5, 'a', 'b'})
+		b.Close()
+	}()
+	res := []interface{}{}
+	rxgo.FromConn(a, rxgo.LengthPrefixFraming).Subscribe(collectItems(&res))
+	if assert.Equal(t, 1, len(res), "truncated frame") {
+		assert.Equal(t, io.ErrUnexpectedEOF, res[0], "truncated frame error")
+	}
+}
+
+func TestFromConnUnsubscribe(t *testing.T) {
+	a, b := net.Pipe()
+	defer b.Close()
+	sub := rxgo.FromConn(a, rxgo.NewlineFraming).SubscribeAsync(func(x []byte) {})
+	done := make(chan struct{})
+	go func() {
+		sub.Dispose()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Error("blocked read is not interrupted by unsubscription")
+		return
+	}
+
+	// the read deadline is cleared
+	go b.Write([]byte("x\n"))
+	buf := make([]byte, 2)
+	n, err := a.Read(buf)
+	assert.NoError(t, err, "read after unsubscription error")
+	assert.Equal(t, "x\n", string(buf[:n]), "read after unsubscription")
+}
+
+func TestToConnCancel(t *testing.T) {
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+
+	// the peer does not read
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- rxgo.Just("abc").ToConn(ctx, a, rxgo.NewlineFraming)
+	}()
+	select {
+	case err := <-done:
+		assert.Equal(t, context.DeadlineExceeded, err, "canceled error")
+	case <-time.After(5 * time.Second):
+		t.Error("blocked write is not interrupted by ctx")
+		return
+	}
+
+	// the write deadline is cleared
+	go io.Copy(io.Discard, b)
+	err := rxgo.Just("de").ToConn(context.Background(), a, rxgo.NewlineFraming)
+	assert.NoError(t, err, "write after cancel error")
+}
@@ -0,0 +1,132 @@
+// Copyright 2018 The SS.SYSU Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rxgo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Graph is a model of a chain of Observables, from the source to the described Observable
+type Graph struct {
+	Nodes []GraphNode
+	Edges []GraphEdge
+}
+
+// GraphNode is a stage in Graph
+type GraphNode struct {
+	ID        string
+	Name      string
+	Kind      string // operator kind, such as "map" or "Just"
+	Threading ThreadModel
+	BufferLen uint
+	Inner     bool // stage of an inner Observable
+	Dynamic   bool // inner Observables of the stage are created for each item, such as FlatMap
+}
+
+// GraphEdge is a flow between stages in Graph
+type GraphEdge struct {
+	From  string
+	To    string
+	Inner bool // flow from an inner Observable to the stage merging it
+}
+
+// Describe returns the graph of stages from the source to this Observable.
+// Inner Observables of FlatMap are included if any was created by a subscription.
+func (o *Observable) Describe() *Graph {
+	g := &Graph{}
+	g.describe(o, false)
+	return g
+}
+
+// add chain ending with o, returns id of the last node
+func (g *Graph) describe(o *Observable, inner bool) string {
+	var chain []*Observable
+	for po := o; po != nil; po = po.pred {
+		chain = append([]*Observable{po}, chain...)
+	}
+
+	op := o.chainOptions()
+	var pred string
+	for _, po := range chain {
+		id := fmt.Sprintf("n%d", len(g.Nodes))
+		g.Nodes = append(g.Nodes, GraphNode{
+			ID:        id,
+			Name:      po.Name,
+			Kind:      po.kind,
+			Threading: po.stageThreading(op),
+			BufferLen: po.stageBufferLen(op),
+			Inner:     inner,
+			Dynamic:   po.kind == "flatMap",
+		})
+		if pred != "" {
+			g.Edges = append(g.Edges, GraphEdge{From: pred, To: id})
+		}
+
+		inners := po.inner
+		if ro := po.lastInner.Load(); ro != nil {
+			inners = append(inners[:len(inners):len(inners)], ro)
+		}
+		for _, ro := range inners {
+			from := g.describe(ro, true)
+			g.Edges = append(g.Edges, GraphEdge{From: from, To: id, Inner: true})
+		}
+		pred = id
+	}
+	return pred
+}
+
+func (n GraphNode) label() string {
+	return fmt.Sprintf("%s\nkind=%s threading=%s buffer=%d", n.Name, n.Kind, n.Threading, n.BufferLen)
+}
+
+// DOT renders the graph in Graphviz DOT language
+func (g *Graph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph rxgo {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box];\n")
+	for _, n := range g.Nodes {
+		attrs := fmt.Sprintf("label=%q", n.label())
+		if n.Inner {
+			attrs += ", style=dashed"
+		}
+		if n.Dynamic {
+			attrs += ", peripheries=2"
+		}
+		fmt.Fprintf(&b, "  %s [%s];\n", n.ID, attrs)
+	}
+	for _, e := range g.Edges {
+		if e.Inner {
+			fmt.Fprintf(&b, "  %s -> %s [style=dashed];\n", e.From, e.To)
+		} else {
+			fmt.Fprintf(&b, "  %s -> %s;\n", e.From, e.To)
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Mermaid renders the graph as a Mermaid flowchart
+func (g *Graph) Mermaid() string {
+	r := strings.NewReplacer("\n", "<br/>", `"`, "#quot;")
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for _, n := range g.Nodes {
+		if n.Dynamic {
+			fmt.Fprintf(&b, "  %s[[\"%s\"]]\n", n.ID, r.Replace(n.label()))
+		} else {
+			fmt.Fprintf(&b, "  %s[\"%s\"]\n", n.ID, r.Replace(n.label()))
+		}
+	}
+	for _, e := range g.Edges {
+		if e.Inner {
+			fmt.Fprintf(&b, "  %s -.-> %s\n", e.From, e.To)
+		} else {
+			fmt.Fprintf(&b, "  %s --> %s\n", e.From, e.To)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,58 @@
+package rxgo_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pmlpml/rxgo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDescribe(t *testing.T) {
+	inner := rxgo.Just(1, 2).Map(func(x int) int {
+		return x
+	})
+	ob := rxgo.From(inner).Filter(func(x int) bool {
+		return true
+	}).SubscribeOn(rxgo.ThreadingIO).SetBufferLen(4)
+
+	g := ob.Describe()
+	kinds := []string{}
+	for _, n := range g.Nodes {
+		kinds = append(kinds, n.Kind)
+	}
+	assert.Equal(t, []string{"From *Observable", "Just", "map", "filter"}, kinds, "nodes error")
+	assert.True(t, g.Nodes[1].Inner, "inner node error")
+	assert.Equal(t, rxgo.ThreadingIO, g.Nodes[3].Threading, "threading error")
+	assert.Equal(t, uint(4), g.Nodes[3].BufferLen, "buffer error")
+	assert.Equal(t, []rxgo.GraphEdge{
+		{From: "n1", To: "n2"},
+		{From: "n2", To: "n0", Inner: true},
+		{From: "n0", To: "n3"},
+	}, g.Edges, "edges error")
+
+	dot := g.DOT()
+	assert.True(t, strings.HasPrefix(dot, "digraph rxgo {"), "dot header error")
+	assert.Contains(t, dot, "n2 -> n0 [style=dashed];", "dot inner edge error")
+	assert.Contains(t, dot, `n3 [label="filter\nkind=filter threading=io buffer=4"];`, "dot node error")
+
+	mermaid := g.Mermaid()
+	assert.True(t, strings.HasPrefix(mermaid, "flowchart LR\n"), "mermaid header error")
+	assert.Contains(t, mermaid, "n0 --> n3", "mermaid edge error")
+	assert.Contains(t, mermaid, "n2 -.-> n0", "mermaid inner edge error")
+}
+
+func TestDescribeFlatMap(t *testing.T) {
+	ob := rxgo.Just(1).FlatMap(func(x int) *rxgo.Observable {
+		return rxgo.Range(0, x)
+	})
+	g := ob.Describe()
+	assert.Equal(t, 2, len(g.Nodes), "nodes before subscription error")
+	assert.True(t, g.Nodes[1].Dynamic, "dynamic node error")
+
+	ob.Subscribe(func(x int) {})
+	g = ob.Describe()
+	assert.Equal(t, 3, len(g.Nodes), "nodes after subscription error")
+	assert.Equal(t, "Range", g.Nodes[2].Kind, "inner node error")
+	assert.Contains(t, g.Mermaid(), `n1[["flatMap<br/>`, "mermaid dynamic node error")
+}
@@ -0,0 +1,109 @@
+// Copyright 2018 The SS.SYSU Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rxgo
+
+import (
+	"context"
+	"reflect"
+	"time"
+)
+
+// fastFunc calls a user function without reflection.
+// ok is false if the item is not the type of the parameter, then the reflective path is used
+type fastFunc func(x interface{}) (res interface{}, ok bool)
+
+// get fastFunc of common signatures of user function, nil if not supported
+func fastFuncOf(f interface{}) fastFunc {
+	switch f := f.(type) {
+	// Map
+	case func(interface{}) interface{}:
+		return fastOf(f)
+	case func(int) int:
+		return fastOf(f)
+	case func(int64) int64:
+		return fastOf(f)
+	case func(float64) float64:
+		return fastOf(f)
+	case func(string) string:
+		return fastOf(f)
+	case func(int) string:
+		return fastOf(f)
+	case func(string) int:
+		return fastOf(f)
+	case func(int) interface{}:
+		return fastOf(f)
+	case func(string) interface{}:
+		return fastOf(f)
+	// Filter
+	case func(interface{}) bool:
+		return fastOf(f)
+	case func(int) bool:
+		return fastOf(f)
+	case func(int64) bool:
+		return fastOf(f)
+	case func(float64) bool:
+		return fastOf(f)
+	case func(string) bool:
+		return fastOf(f)
+	// FlatMap
+	case func(interface{}) *Observable:
+		return fastOf(f)
+	case func(int) *Observable:
+		return fastOf(f)
+	case func(string) *Observable:
+		return fastOf(f)
+	}
+	return nil
+}
+
+func fastOf[T, R any](f func(T) R) fastFunc {
+	return func(x interface{}) (interface{}, bool) {
+		if v, ok := x.(T); ok {
+			return f(v), true
+		}
+		return nil, false
+	}
+}
+
+// call flip function of stage o with item x and returns its first result, the time of it is recorded to metrics of out.
+// The fast path is used if the flip function has a common signature and x is the type of its parameter
+func (o *Observable) callFlip(ctx context.Context, x reflect.Value, out *flow) (res interface{}, skip, stop bool, eout error) {
+	if m := out.metrics(); m != nil {
+		defer func(start time.Time) {
+			m.ItemProcessed(o.stageName(out.options), time.Since(start))
+		}(time.Now())
+	}
+	item := itemOf(x)
+	if o.flip_fast != nil {
+		var ok bool
+		if res, ok, skip, stop, eout = fastFuncCall(ctx, o, item, o.flip_fast); ok {
+			return
+		}
+	}
+
+	params, e := o.flipParams(ctx, x)
+	if e != nil {
+		eout = e
+		return
+	}
+	rs, skip, stop, eout := userFuncCall(ctx, o, item, reflect.ValueOf(o.flip), params)
+	if eout == nil && len(rs) > 0 {
+		res = rs[0].Interface()
+	}
+	return
+}
+
+// wrap exception when call fastFunc of stage o with the item
+func fastFuncCall(ctx context.Context, o *Observable, item interface{}, f fastFunc) (res interface{}, ok, skip, stop bool, eout error) {
+	defer func() {
+		if e := recover(); e != nil {
+			ok = true
+			skip, stop, eout = o.userPanic(ctx, item, e)
+		}
+	}()
+
+	res, ok = f(item)
+	return
+}
@@ -0,0 +1,104 @@
+package rxgo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pmlpml/rxgo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFastPath(t *testing.T) {
+	res := []interface{}{}
+	rxgo.Just(1, 2, int64(3), 4, 5).Map(func(x int) int {
+		if x == 4 {
+			panic(rxgo.ErrSkipItem)
+		}
+		return 2 * x
+	}).Configure(rxgo.WithCoercion(rxgo.CoercionConvertible)).Filter(func(x interface{}) bool {
+		return x != 2
+	}).Subscribe(func(x int) {
+		res = append(res, x)
+	})
+	assert.Equal(t, []interface{}{4, 6, 10}, res, "fast path items error")
+
+	var errs []error
+	rxgo.Just("a", 1).Map(func(x string) string {
+		return x + x
+	}).Subscribe(rxgo.ObserverMonitor{
+		Error: func(e error) {
+			errs = append(errs, e)
+		},
+	})
+	var te *rxgo.ItemTypeError
+	assert.Equal(t, 1, len(errs), "errors count error")
+	assert.True(t, errors.As(errs[0], &te), "not an ItemTypeError")
+}
+
+// number type which has no fast path
+type number int
+
+const benchItems = 10000
+
+func benchmarkMap(b *testing.B, items []interface{}, f interface{}) {
+	for i := 0; i < b.N; i++ {
+		rxgo.From(items).Map(f).Subscribe(func(x interface{}) {})
+	}
+}
+
+func BenchmarkMapFast(b *testing.B) {
+	items := make([]interface{}, benchItems)
+	for i := range items {
+		items[i] = i
+	}
+	benchmarkMap(b, items, func(x int) int {
+		return x + 1
+	})
+}
+
+func BenchmarkMapReflect(b *testing.B) {
+	items := make([]interface{}, benchItems)
+	for i := range items {
+		items[i] = number(i)
+	}
+	benchmarkMap(b, items, func(x number) number {
+		return x + 1
+	})
+}
+
+func BenchmarkMapReflectWithContext(b *testing.B) {
+	items := make([]interface{}, benchItems)
+	for i := range items {
+		items[i] = i
+	}
+	benchmarkMap(b, items, func(ctx context.Context, x int) int {
+		return x + 1
+	})
+}
+
+func benchmarkFilter(b *testing.B, items []interface{}, f interface{}) {
+	for i := 0; i < b.N; i++ {
+		rxgo.From(items).Filter(f).Subscribe(func(x interface{}) {})
+	}
+}
+
+func BenchmarkFilterFast(b *testing.B) {
+	items := make([]interface{}, benchItems)
+	for i := range items {
+		items[i] = i
+	}
+	benchmarkFilter(b, items, func(x int) bool {
+		return x%2 == 0
+	})
+}
+
+func BenchmarkFilterReflect(b *testing.B) {
+	items := make([]interface{}, benchItems)
+	for i := range items {
+		items[i] = number(i)
+	}
+	benchmarkFilter(b, items, func(x number) bool {
+		return x%2 == 0
+	})
+}
@@ -14,14 +14,14 @@ var (
 )
 
 type filterOperator struct {
-	opFunc func(ctx context.Context, o *Observable, item reflect.Value, out chan interface{}) (end bool)
+	opFunc func(ctx context.Context, o *Observable, item reflect.Value, out *flow) (end bool)
 }
 
 func (parent *Observable) newFilterObservable(name string) (o *Observable) {
 	o = newObservable()
 	o.Name = name
+	o.kind = name
 
-	parent.next = o
 	o.pred = parent
 	o.root = parent.root
 
@@ -40,12 +40,12 @@ func (parent *Observable) First() (o *Observable) {
 	return o
 }
 
-var firstOperator = filterOperator{func(ctx context.Context, o *Observable, x reflect.Value, out chan interface{}) (end bool) {
+var firstOperator = filterOperator{func(ctx context.Context, o *Observable, x reflect.Value, out *flow) (end bool) {
 	var params = []reflect.Value{x}
 	x = params[0]
 
 	if !end {
-		end = o.sendToFlow(ctx, x.Interface(), out)
+		end = o.sendToFlow(ctx, itemOf(x), out)
 	}
 	return
 },
@@ -62,12 +62,12 @@ func (parent *Observable) Last() (o *Observable) {
 	return o
 }
 
-var lastOperator = filterOperator{func(ctx context.Context, o *Observable, x reflect.Value, out chan interface{}) (end bool) {
+var lastOperator = filterOperator{func(ctx context.Context, o *Observable, x reflect.Value, out *flow) (end bool) {
 	var params = []reflect.Value{x}
 	x = params[0]
 
 	if !end {
-		end = o.sendToFlow(ctx, x.Interface(), out)
+		end = o.sendToFlow(ctx, itemOf(x), out)
 	}
 	return
 },
@@ -82,12 +82,12 @@ func (parent *Observable) Debounce(_debounce time.Duration) (o *Observable) {
 	return o
 }
 
-var debounceOperator = filterOperator{opFunc: func(ctx context.Context, o *Observable, x reflect.Value, out chan interface{}) (end bool) {
+var debounceOperator = filterOperator{opFunc: func(ctx context.Context, o *Observable, x reflect.Value, out *flow) (end bool) {
 	var params = []reflect.Value{x}
 	x = params[0]
 
 	if !end {
-		end = o.sendToFlow(ctx, x.Interface(), out)
+		end = o.sendToFlow(ctx, itemOf(x), out)
 	}
 	return
 },
@@ -102,12 +102,12 @@ func (parent *Observable) Distinct() (o *Observable) {
 	return o
 }
 
-var distinctOperator = filterOperator{opFunc: func(ctx context.Context, o *Observable, x reflect.Value, out chan interface{}) (end bool) {
+var distinctOperator = filterOperator{opFunc: func(ctx context.Context, o *Observable, x reflect.Value, out *flow) (end bool) {
 	var params = []reflect.Value{x}
 	x = params[0]
 
 	if !end {
-		end = o.sendToFlow(ctx, x.Interface(), out)
+		end = o.sendToFlow(ctx, itemOf(x), out)
 	}
 	return
 },
@@ -122,12 +122,12 @@ func (parent *Observable) Sample(_sample time.Duration) (o *Observable) {
 	return o
 }
 
-var sampleOperator = filterOperator{opFunc: func(ctx context.Context, o *Observable, x reflect.Value, out chan interface{}) (end bool) {
+var sampleOperator = filterOperator{opFunc: func(ctx context.Context, o *Observable, x reflect.Value, out *flow) (end bool) {
 	var params = []reflect.Value{x}
 	x = params[0]
 
 	if !end {
-		end = o.sendToFlow(ctx, x.Interface(), out)
+		end = o.sendToFlow(ctx, itemOf(x), out)
 	}
 	return
 },
@@ -142,12 +142,12 @@ func (parent *Observable) Skip(num int) (o *Observable) {
 	return o
 }
 
-var skipOperator = filterOperator{opFunc: func(ctx context.Context, o *Observable, x reflect.Value, out chan interface{}) (end bool) {
+var skipOperator = filterOperator{opFunc: func(ctx context.Context, o *Observable, x reflect.Value, out *flow) (end bool) {
 	var params = []reflect.Value{x}
 	x = params[0]
 
 	if !end {
-		end = o.sendToFlow(ctx, x.Interface(), out)
+		end = o.sendToFlow(ctx, itemOf(x), out)
 	}
 	return
 },
@@ -165,12 +165,12 @@ func (parent *Observable) ElementAt(index int) (o *Observable) {
 	return
 }
 
-var elementAtOperator = filterOperator{opFunc: func(ctx context.Context, o *Observable, x reflect.Value, out chan interface{}) (end bool) {
+var elementAtOperator = filterOperator{opFunc: func(ctx context.Context, o *Observable, x reflect.Value, out *flow) (end bool) {
 	var params = []reflect.Value{x}
 	x = params[0]
 
 	if !end {
-		end = o.sendToFlow(ctx, x.Interface(), out)
+		end = o.sendToFlow(ctx, itemOf(x), out)
 	}
 	return
 },
@@ -185,12 +185,12 @@ func (parent *Observable) SkipLast(num int) (o *Observable) {
 	return o
 }
 
-var skipLastOperator = filterOperator{opFunc: func(ctx context.Context, o *Observable, x reflect.Value, out chan interface{}) (end bool) {
+var skipLastOperator = filterOperator{opFunc: func(ctx context.Context, o *Observable, x reflect.Value, out *flow) (end bool) {
 	var params = []reflect.Value{x}
 	x = params[0]
 
 	if !end {
-		end = o.sendToFlow(ctx, x.Interface(), out)
+		end = o.sendToFlow(ctx, itemOf(x), out)
 	}
 	return
 },
@@ -205,12 +205,12 @@ func (parent *Observable) Take(num int) (o *Observable) {
 	return o
 }
 
-var takeOperator = filterOperator{opFunc: func(ctx context.Context, o *Observable, x reflect.Value, out chan interface{}) (end bool) {
+var takeOperator = filterOperator{opFunc: func(ctx context.Context, o *Observable, x reflect.Value, out *flow) (end bool) {
 	var params = []reflect.Value{x}
 	x = params[0]
 
 	if !end {
-		end = o.sendToFlow(ctx, x.Interface(), out)
+		end = o.sendToFlow(ctx, itemOf(x), out)
 	}
 	return
 },
@@ -225,12 +225,12 @@ func (parent *Observable) TakeLast(num int) (o *Observable) {
 	return o
 }
 
-var takeLastOperator = filterOperator{opFunc: func(ctx context.Context, o *Observable, x reflect.Value, out chan interface{}) (end bool) {
+var takeLastOperator = filterOperator{opFunc: func(ctx context.Context, o *Observable, x reflect.Value, out *flow) (end bool) {
 	var params = []reflect.Value{x}
 	x = params[0]
 
 	if !end {
-		end = o.sendToFlow(ctx, x.Interface(), out)
+		end = o.sendToFlow(ctx, itemOf(x), out)
 	}
 	return
 },
@@ -260,142 +260,141 @@ func takeOrSkip(_op bool, div int, in []interface{}) ([]interface{}, error) {
 	return nil, OutOfBound
 }
 
-func (tsop filterOperator) op(ctx context.Context, o *Observable) {
-	in := o.pred.outflow
-	out := o.outflow
+func (tsop filterOperator) op(ctx context.Context, o *Observable, in, out *flow) {
+	sch := newScheduler(ctx, o)
 	var wg sync.WaitGroup
 
 	// 设置时间间隔
 	tspan := o.debounce
 	var _out []interface{}
 
-	go func() {
-		end := false
-		flag := make(map[interface{}]bool)
+	flag := make(map[interface{}]bool)
 
-		// 获取开始的时间
-		start := time.Now()
-		sample_start := time.Now()
+	// 获取开始的时间
+	start := time.Now()
+	sample_start := time.Now()
 
-		for x := range in {
+	// flag, _out and sample_start are only used by the receiver, operations get their own item.
+	// _out keeps items as received, so the span contexts of them are restored when sent on completion
+	o.receive(ctx, in, out, &receiver{
+		next: func(x interface{}) (stop bool) {
 			_start := time.Since(start)
 			_sample := time.Since(sample_start)
 			start = time.Now()
 
-			if end {
-				continue
+			if sch.ended() {
+				return true
 			}
+			raw := x
+			ictx, x := recvItem(ctx, x)
 
 			if o.sample > 0 && _sample < o.sample {
-				continue
+				return false
 			}
 
 			if tspan > time.Duration(0) && _start < tspan {
-				continue
+				return false
 			}
 			xv := reflect.ValueOf(x)
 			if e, ok := x.(error); ok && !o.flip_accept_error {
-				o.sendToFlow(ctx, e, out)
-				continue
+				o.sendToFlow(ictx, e, out)
+				return false
 			}
 
-			o.mu.Lock()
-			_out = append(_out, x)
-			o.mu.Unlock()
+			_out = append(_out, raw)
 
 			if o.elementAt > 0 {
-				continue
+				return false
 			}
 
 			if o.take != 0 || o.skip != 0 {
-				continue
+				return false
 			}
 
 			if o.last {
-				continue
+				return false
 			}
 
-			if o.distinct && flag[xv.Interface()] {
-				continue
-			}
-			o.mu.Lock()
-			flag[xv.Interface()] = true
-			o.mu.Unlock()
-
-			switch threading := o.threading; threading {
-			case ThreadingDefault:
-				if o.sample > 0 {
-					sample_start = sample_start.Add(o.sample)
+			if o.distinct {
+				// items of Distinct are map keys
+				if xv.IsValid() && !xv.Comparable() {
+					o.sendToFlow(ictx, &ItemTypeError{Stage: o.stageName(out.options), Item: x}, out)
+					return false
 				}
-				if tsop.opFunc(ctx, o, xv, out) {
-					end = true
+				if flag[x] {
+					return false
 				}
-			case ThreadingIO:
-				fallthrough
-			case ThreadingComputing:
-				wg.Add(1)
-				if o.sample > 0 {
-					sample_start.Add(o.sample)
+				flag[x] = true
+			}
+
+			if o.sample > 0 {
+				sample_start = sample_start.Add(o.sample)
+			}
+			if sch.threading == ThreadingDefault {
+				// without a closure as transOperater
+				if tsop.opFunc(ictx, o, xv, out) {
+					sch.end.Store(true)
 				}
+			} else {
+				sch.run(func() bool {
+					return tsop.opFunc(ictx, o, xv, out)
+				})
+			}
+			// stop stages before after the first item
+			return o.first || sch.ended()
+		},
+		complete: func() {
+			if o.last && len(_out) > 0 {
+				wg.Add(1)
+				o.goroutineStarted(ctx)
 				go func() {
 					defer wg.Done()
-					if tsop.opFunc(ctx, o, xv, out) {
-						end = true
-					}
+					defer o.goroutineStopped(ctx)
+					ictx, x := recvItem(ctx, _out[len(_out)-1])
+					tsop.opFunc(ictx, o, reflect.ValueOf(x), out)
 				}()
-			default:
-			}
-			if o.first {
-				break
 			}
-		}
-
-		if o.last && len(_out) > 0 {
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
-				xv := reflect.ValueOf(_out[len(_out)-1])
-				tsop.opFunc(ctx, o, xv, out)
-			}()
-		}
 
-		if o.take != 0 || o.skip != 0 {
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
-				var div int
-				if o.takeOrLast {
-					div = o.take
-				} else {
-					div = o.skip
-				}
-				new_in, err := takeOrSkip(o.takeOrLast, div, _out)
+			if o.take != 0 || o.skip != 0 {
+				wg.Add(1)
+				o.goroutineStarted(ctx)
+				go func() {
+					defer wg.Done()
+					defer o.goroutineStopped(ctx)
+					var div int
+					if o.takeOrLast {
+						div = o.take
+					} else {
+						div = o.skip
+					}
+					new_in, err := takeOrSkip(o.takeOrLast, div, _out)
+
+					if err != nil {
+						o.sendToFlow(ctx, err, out)
+					} else {
+						for _, raw := range new_in {
+							ictx, x := recvItem(ctx, raw)
+							tsop.opFunc(ictx, o, reflect.ValueOf(x), out)
+						}
+					}
+				}()
+			}
 
-				if err != nil {
-					o.sendToFlow(ctx, err, out)
+			if o.elementAt != 0 {
+				if o.elementAt < 0 || o.elementAt > len(_out) {
+					o.sendToFlow(ctx, OutOfBound, out)
 				} else {
-					xv := new_in
-					for _, val := range xv {
-						tsop.opFunc(ctx, o, reflect.ValueOf(val), out)
-					}
+					ictx, x := recvItem(ctx, _out[o.elementAt-1])
+					tsop.opFunc(ictx, o, reflect.ValueOf(x), out)
 				}
-			}()
-		}
-
-		if o.elementAt != 0 {
-			if o.elementAt < 0 || o.elementAt > len(_out) {
-				o.sendToFlow(ctx, OutOfBound, out)
-			} else {
-				xv := reflect.ValueOf(_out[o.elementAt-1])
-				tsop.opFunc(ctx, o, xv, out)
 			}
-		}
-
-		wg.Wait()
-		if (o.last || o.first) && len(_out) == 0 && !o.flip_accept_error {
-			o.sendToFlow(ctx, NoInput, out)
-		}
-		o.closeFlow(out)
-	}()
 
+			sch.wait()
+			wg.Wait()
+			if (o.last || o.first) && len(_out) == 0 && !o.flip_accept_error {
+				o.sendToFlow(ctx, NoInput, out)
+			}
+			o.closeFlow(out)
+		},
+	})
 }
@@ -5,23 +5,31 @@
 package rxgo
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"io"
+	"os"
 	"reflect"
+	"sync"
+	"time"
 )
 
 // source node implementation of streamOperator
 type sourceOperater struct {
-	opFunc func(ctx context.Context, o *Observable, out chan interface{}) (end bool)
+	opFunc func(ctx context.Context, o *Observable, out *flow) (end bool)
 }
 
-func (sop sourceOperater) op(ctx context.Context, o *Observable) {
-	// must hold defintion of flow resourcs here, such as chan etc., that is allocated when connected
-	// this resurces may be changed when operation routine is running.
-	out := o.outflow
+func (sop sourceOperater) op(ctx context.Context, o *Observable, in, out *flow) {
+	// flow resourcs, such as chan etc., are allocated for each subscription when connected
 	//fmt.Println(o.name, "source out chan ", out)
 
 	// Scheduler
+	o.goroutineStarted(ctx)
+	out.wg.Add(1)
 	go func() {
+		defer out.wg.Done()
+		defer o.goroutineStopped(ctx)
 		for end := false; !end; { // made panic op re-enter
 			end = sop.opFunc(ctx, o, out)
 		}
@@ -36,37 +44,45 @@ func Generator(sf sourceFunc) *Observable {
 	return o
 }
 
-var sourceSource = sourceOperater{func(ctx context.Context, o *Observable, out chan interface{}) (end bool) {
+var sourceSource = sourceOperater{func(ctx context.Context, o *Observable, out *flow) (end bool) {
 	sf := o.flip.(sourceFunc)
 	send := func(x interface{}) (endSignal bool) {
 		endSignal = o.sendToFlow(ctx, x, out)
 		return
 	}
+	defer func() {
+		if e := recover(); e != nil {
+			o.sendToFlow(ctx, o.operatorError(ctx, nil, e), out)
+			end = true
+		}
+	}()
 	sf(ctx, send)
 	return true
 }}
 
 // creates an Observable with the provided item(s) producing by the function `func()  (val anytype, end bool)`
+// or `func() (val anytype, end bool, err error)`
 func Start(f interface{}) *Observable {
 	fv := reflect.ValueOf(f)
 	inType := []reflect.Type{}
 	outType := []reflect.Type{typeAny, typeBool}
-	ctx_sup := false
-	if b, cb := checkFuncUpcast(fv, inType, outType, true); !b {
+	ctx_sup, err_sup := false, false
+	if b, cb, eb := checkFuncUpcast(fv, inType, outType, true, true); !b {
 		panic(ErrFuncFlip)
 	} else {
-		ctx_sup = cb
+		ctx_sup, err_sup = cb, eb
 	}
 
 	o := newGeneratorObservable("Start")
 	o.flip_sup_ctx = ctx_sup
+	o.flip_ret_error = err_sup
 
 	o.flip = fv.Interface()
 	o.operator = startSource
 	return o
 }
 
-var startSource = sourceOperater{func(ctx context.Context, o *Observable, out chan interface{}) (end bool) {
+var startSource = sourceOperater{func(ctx context.Context, o *Observable, out *flow) (end bool) {
 	fv := reflect.ValueOf(o.flip)
 	params := []reflect.Value{}
 	if o.flip_sup_ctx {
@@ -74,25 +90,31 @@ var startSource = sourceOperater{func(ctx context.Context, o *Observable, out ch
 	}
 
 	for end := false; !end; {
-		rs, skip, stop, e := userFuncCall(fv, params)
+		rs, skip, stop, e := userFuncCall(ctx, o, nil, fv, params)
 
-		var item interface{}
 		if stop {
 			return true
 		}
 		if skip {
 			continue
 		}
-		if e != nil {
-			item = e
-		}
 		if len(rs) > 0 {
 			end, _ = (rs[1].Interface()).(bool)
-			item = rs[0].Interface()
+		}
+		if e != nil {
+			// the generator is broken, stop it after sending the error
+			if _, ok := e.(*OperatorError); ok {
+				end = true
+			}
+			// the error is sent even if the generator ends with it
+			if b := o.sendToFlow(ctx, e, out); b {
+				end = true
+			}
+			continue
 		}
 		// send data
 		if !end {
-			end = o.sendToFlow(ctx, item, out)
+			end = o.sendToFlow(ctx, rs[0].Interface(), out)
 		}
 	}
 
@@ -103,7 +125,7 @@ var startSource = sourceOperater{func(ctx context.Context, o *Observable, out ch
 func Range(start, end int) *Observable {
 	o := newGeneratorObservable("Range")
 
-	o.flip = func(ctx context.Context, out chan interface{}) {
+	o.flip = func(ctx context.Context, out *flow) {
 		i := start
 		for i < end {
 			if b := o.sendToFlow(ctx, i, out); b {
@@ -116,7 +138,14 @@ func Range(start, end int) *Observable {
 	return o
 }
 
-var rangeSource = sourceOperater{func(ctx context.Context, o *Observable, out chan interface{}) (end bool) {
+// rangeSource runs the flip function of a generator, a panic of user code run by it is sent as OperatorError
+var rangeSource = sourceOperater{func(ctx context.Context, o *Observable, out *flow) (end bool) {
+	defer func() {
+		if e := recover(); e != nil {
+			o.sendToFlow(ctx, o.operatorError(ctx, nil, e), out)
+			end = true
+		}
+	}()
 	fv := reflect.ValueOf(o.flip)
 	params := []reflect.Value{reflect.ValueOf(ctx), reflect.ValueOf(out)}
 	fv.Call(params)
@@ -127,7 +156,7 @@ var rangeSource = sourceOperater{func(ctx context.Context, o *Observable, out ch
 func Just(items ...interface{}) *Observable {
 	o := newGeneratorObservable("Just")
 
-	o.flip = func(ctx context.Context, out chan interface{}) {
+	o.flip = func(ctx context.Context, out *flow) {
 		for _, item := range items {
 			if b := o.sendToFlow(ctx, item, out); b {
 				return
@@ -151,7 +180,7 @@ func From(items interface{}) *Observable {
 		length := v.Len()
 		o := newGeneratorObservable("From Slice")
 
-		o.flip = func(ctx context.Context, out chan interface{}) {
+		o.flip = func(ctx context.Context, out *flow) {
 			i := 0
 			for i < length {
 				item := v.Index(i).Interface()
@@ -168,7 +197,7 @@ func From(items interface{}) *Observable {
 	if v.Kind() == reflect.Chan {
 		o := newGeneratorObservable("From Channel")
 
-		o.flip = func(ctx context.Context, out chan interface{}) {
+		o.flip = func(ctx context.Context, out *flow) {
 			for {
 				// details: https://godoc.org/reflect#Select
 				var selectcases = []reflect.SelectCase{
@@ -198,26 +227,281 @@ func From(items interface{}) *Observable {
 	//fmt.Println(t, st)
 	if t == st {
 		o := newGeneratorObservable("From *Observable")
+		o.inner = []*Observable{v.Interface().(*Observable)}
 
-		o.flip = func(ctx context.Context, out chan interface{}) {
+		o.flip = func(ctx context.Context, out *flow) {
 			ro := v.Interface().(*Observable)
-			for ; ro.next != nil; ro = ro.next {
+			ch := ro.connect(ctx)
+			defer ch.dispose()
+			ch.forEach(ctx, func(x interface{}) bool {
+				ictx, item := recvItem(ctx, x)
+				return o.sendToFlow(ictx, item, out)
+			})
+		}
+		o.operator = fromObservable
+		return o
+	}
+
+	panic(ErrFuncFlip)
+}
+
+// flipSource runs the flip function `func(ctx context.Context, out *flow)` of a generator
+var flipSource = rangeSource
+
+// FromLines creates an Observable of lines without end-of-line markers read from r
+func FromLines(r io.Reader) *Observable {
+	o := FromScanner(r, bufio.ScanLines)
+	o.Name = "FromLines"
+	o.kind = "FromLines"
+	return o
+}
+
+// FromScanner creates an Observable of string tokens split by the split function from r, such as bufio.ScanWords.
+// r is closed on completion or unsubscription if it is an io.Closer, and a read error is sent before completion,
+// or OperatorError if split or the scanner panics. r can be read only once, so the Observable is subscribed once.
+func FromScanner(r io.Reader, split bufio.SplitFunc) *Observable {
+	o := newGeneratorObservable("FromScanner")
+
+	o.flip = func(ctx context.Context, out *flow) {
+		defer watchReader(ctx, r)()
+		sc := bufio.NewScanner(r)
+		sc.Split(split)
+		for sc.Scan() {
+			if b := o.sendToFlow(ctx, sc.Text(), out); b {
+				return
 			}
-			ro.mu.Lock()
-			ro.connect(ctx)
-			ch := ro.outflow
-			ro.mu.Unlock()
-			for item := range ch {
-				if b := o.sendToFlow(ctx, item, out); b {
+		}
+		if e := sc.Err(); e != nil && ctx.Err() == nil {
+			o.sendToFlow(ctx, e, out)
+		}
+	}
+	o.operator = flipSource
+	return o
+}
+
+// FromReader creates an Observable of []byte chunks of size bytes read from r, the last chunk may be shorter.
+// r is closed on completion or unsubscription if it is an io.Closer, and a read error is sent before completion.
+// r can be read only once, so the Observable is subscribed once.
+func FromReader(r io.Reader, size int) *Observable {
+	if size <= 0 {
+		panic(ErrFuncFlip)
+	}
+	o := newGeneratorObservable("FromReader")
+
+	o.flip = func(ctx context.Context, out *flow) {
+		defer watchReader(ctx, r)()
+		for {
+			buf := make([]byte, size)
+			n, e := io.ReadFull(r, buf)
+			if n > 0 {
+				if b := o.sendToFlow(ctx, buf[:n], out); b {
 					return
 				}
 			}
+			if e == io.EOF || e == io.ErrUnexpectedEOF {
+				return
+			}
+			if e != nil {
+				if ctx.Err() == nil {
+					o.sendToFlow(ctx, e, out)
+				}
+				return
+			}
 		}
-		o.operator = fromObservable
-		return o
 	}
+	o.operator = flipSource
+	return o
+}
 
-	panic(ErrFuncFlip)
+// close r if it is an io.Closer when ctx is done, which unblocks reading of r, or when the returned function is called
+func watchReader(ctx context.Context, r io.Reader) (stop func()) {
+	c, ok := r.(io.Closer)
+	if !ok {
+		return func() {}
+	}
+	return watchCloser(ctx, c)
+}
+
+// close c on ctx done or when stop is called
+func watchCloser(ctx context.Context, c io.Closer) (stop func()) {
+	var once sync.Once
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			once.Do(func() { c.Close() })
+		case <-done:
+		}
+	}()
+	return func() {
+		close(done)
+		once.Do(func() { c.Close() })
+	}
+}
+
+// TailLine is a line appended to the file tailed by TailFile
+type TailLine struct {
+	Text   string // line without end-of-line markers
+	Offset int64  // offset after the line in the file, set it to TailOptions.Offset to resume after this line
+}
+
+// TailOptions of TailFile
+type TailOptions struct {
+	Offset       int64         // offset in the file to start with, it starts from the beginning if the file is shorter
+	FromEnd      bool          // start at the end of the file, Offset is ignored
+	PollInterval time.Duration // interval to check the file, DefaultTailPollInterval if not positive
+}
+
+// default interval of TailFile to check the file for appended lines, rotation and truncation
+var DefaultTailPollInterval = 100 * time.Millisecond
+
+// TailFile creates an Observable of TailLine appended to the file at path like `tail -F`.
+// It waits for the file to be created, follows the new file at path after rotation,
+// and reads from the beginning after truncation. It never completes until unsubscription.
+func TailFile(path string, opts TailOptions) *Observable {
+	o := newGeneratorObservable("TailFile")
+
+	o.flip = func(ctx context.Context, out *flow) {
+		t := &tailer{path: path, interval: opts.PollInterval, offset: opts.Offset, fromEnd: opts.FromEnd}
+		if t.interval <= 0 {
+			t.interval = DefaultTailPollInterval
+		}
+		defer t.close()
+		t.run(ctx, func(x interface{}) bool {
+			return o.sendToFlow(ctx, x, out)
+		})
+	}
+	o.operator = flipSource
+	return o
+}
+
+// tailer reads lines appended to the file at path
+type tailer struct {
+	path     string
+	interval time.Duration
+	f        *os.File
+	r        *bufio.Reader
+	offset   int64  // offset of r in f
+	fromEnd  bool   // open f at the end
+	partial  []byte // last line without end-of-line marker yet
+}
+
+func (t *tailer) run(ctx context.Context, send func(x interface{}) (end bool)) {
+	for {
+		if t.f == nil {
+			if e := t.open(); e != nil && !os.IsNotExist(e) {
+				send(e)
+				return
+			}
+		}
+		if t.f != nil {
+			if end, e := t.readLines(send); end {
+				return
+			} else if e != nil {
+				send(e)
+				return
+			}
+			if end := t.follow(send); end {
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(t.interval):
+		}
+	}
+}
+
+// open the file at path and seek to the offset
+func (t *tailer) open() error {
+	f, e := os.Open(t.path)
+	if e != nil {
+		return e
+	}
+	fi, e := f.Stat()
+	if e != nil {
+		f.Close()
+		return e
+	}
+	if t.fromEnd {
+		t.offset = fi.Size()
+	}
+	if t.offset > fi.Size() {
+		t.offset = 0
+	}
+	if _, e := f.Seek(t.offset, io.SeekStart); e != nil {
+		f.Close()
+		return e
+	}
+	t.f, t.r = f, bufio.NewReader(f)
+	return nil
+}
+
+// send lines until the end of file
+func (t *tailer) readLines(send func(x interface{}) (end bool)) (end bool, e error) {
+	for {
+		line, e := t.r.ReadBytes('\n')
+		t.offset += int64(len(line))
+		t.partial = append(t.partial, line...)
+		if n := len(t.partial); n > 0 && t.partial[n-1] == '\n' {
+			if end := t.sendPartial(send); end {
+				return true, nil
+			}
+		}
+		if e == io.EOF {
+			return false, nil
+		}
+		if e != nil {
+			return false, e
+		}
+	}
+}
+
+// send the partial line as a complete one
+func (t *tailer) sendPartial(send func(x interface{}) (end bool)) (end bool) {
+	text := string(bytes.TrimRight(t.partial, "\r\n"))
+	t.partial = t.partial[:0]
+	return send(TailLine{Text: text, Offset: t.offset})
+}
+
+// check rotation and truncation of the file after the end of it is read
+func (t *tailer) follow(send func(x interface{}) (end bool)) (end bool) {
+	cur, e := t.f.Stat()
+	if e != nil {
+		return false
+	}
+	fi, e := os.Stat(t.path)
+	switch {
+	case e != nil || !os.SameFile(fi, cur):
+		// rotated, read lines written before rotation and follow the new file from its beginning
+		if end, _ := t.readLines(send); end {
+			return true
+		}
+		if len(t.partial) > 0 {
+			if end := t.sendPartial(send); end {
+				return true
+			}
+		}
+		t.close()
+		t.offset, t.fromEnd = 0, false
+	case fi.Size() < t.offset:
+		// truncated
+		if _, e := t.f.Seek(0, io.SeekStart); e == nil {
+			t.r.Reset(t.f)
+			t.offset = 0
+			t.partial = t.partial[:0]
+		}
+	}
+	return false
+}
+
+func (t *tailer) close() {
+	if t.f != nil {
+		t.f.Close()
+		t.f, t.r = nil, nil
+	}
 }
 
 // create an Observable that emits no items and does not terminate.
@@ -230,6 +514,7 @@ func Never() *Observable {
 	}
 	o := Generator(source)
 	o.Name = "Never"
+	o.kind = "Never"
 	return o
 }
 
@@ -240,7 +525,7 @@ var throwSource = rangeSource
 func Empty() *Observable {
 	o := newGeneratorObservable("Empty")
 
-	o.flip = func(ctx context.Context, out chan interface{}) {
+	o.flip = func(ctx context.Context, out *flow) {
 	}
 	o.operator = emptySource
 	return o
@@ -250,7 +535,7 @@ func Empty() *Observable {
 func Throw(e error) *Observable {
 	o := newGeneratorObservable("Throw")
 
-	o.flip = func(ctx context.Context, out chan interface{}) {
+	o.flip = func(ctx context.Context, out *flow) {
 		item := e
 		o.sendToFlow(ctx, item, out)
 	}
@@ -262,6 +547,7 @@ func newGeneratorObservable(name string) (o *Observable) {
 	//new Observable
 	o = newObservable()
 	o.Name = name
+	o.kind = name
 
 	//chain Observables
 	o.root = o
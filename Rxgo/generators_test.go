@@ -1,10 +1,17 @@
 package rxgo_test
 
 import (
+	"bufio"
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"testing/iotest"
 	"time"
 
 	"github.com/pmlpml/rxgo"
@@ -69,6 +76,45 @@ func TestStart(t *testing.T) {
 	assert.Equal(t, []int64{1, 2, 4}, res, "Start Test Error!")
 }
 
+func TestStartWithError(t *testing.T) {
+	i := 0
+	res := []int{}
+	errs := 0
+	rxgo.Start(func() (int, bool, error) {
+		i++
+		switch i {
+		case 2:
+			return 0, false, rxgo.ErrSkipItem
+		case 3:
+			return 0, false, errors.New("any")
+		case 5:
+			return 0, false, rxgo.ErrEoFlow
+		}
+		return i, false, nil
+	}).Subscribe(rxgo.ObserverMonitor{
+		Next: func(x interface{}) {
+			res = append(res, x.(int))
+		},
+		Error: func(e error) {
+			errs++
+		},
+	})
+
+	assert.Equal(t, []int{1, 4}, res, "Start with error Test Error!")
+	assert.Equal(t, 1, errs, "Start with error count Error!")
+
+	// ends with an error
+	calls := 0
+	ee := errors.New("last")
+	items := []interface{}{}
+	rxgo.Start(func() (int, bool, error) {
+		calls++
+		return 0, true, ee
+	}).Subscribe(collectItems(&items))
+	assert.Equal(t, 1, calls, "Start is called after end")
+	assert.Equal(t, []interface{}{ee}, items, "Start end with error")
+}
+
 func TestAnySouce(t *testing.T) {
 	res := []int{}
 	source := func(ctx context.Context, send func(x interface{}) (endSignal bool)) {
@@ -186,3 +232,179 @@ func TestNeverWithCancel(t *testing.T) {
 
 	rxgo.Never().Subscribe(oberver)
 }
+
+// reader records whether it is closed
+type closeReader struct {
+	io.Reader
+	mu     sync.Mutex
+	closed bool
+}
+
+func (r *closeReader) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	return nil
+}
+
+func (r *closeReader) isClosed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.closed
+}
+
+func TestFromLines(t *testing.T) {
+	r := &closeReader{Reader: strings.NewReader("one\ntwo\r\n\nthree")}
+	res := []string{}
+	rxgo.FromLines(r).Subscribe(func(x string) {
+		res = append(res, x)
+	})
+	assert.Equal(t, []string{"one", "two", "", "three"}, res, "lines error")
+	assert.True(t, r.isClosed(), "reader not closed")
+}
+
+func TestFromScanner(t *testing.T) {
+	res := []string{}
+	rxgo.FromScanner(strings.NewReader("a bb  ccc\n"), bufio.ScanWords).Subscribe(func(x string) {
+		res = append(res, x)
+	})
+	assert.Equal(t, []string{"a", "bb", "ccc"}, res, "tokens error")
+
+	// panics of the split function and of bufio are sent as OperatorError
+	for _, split := range []bufio.SplitFunc{
+		func(data []byte, atEOF bool) (int, []byte, error) {
+			panic("bad split")
+		},
+		func(data []byte, atEOF bool) (int, []byte, error) {
+			// empty tokens at EOF without progressing
+			if atEOF {
+				return 0, []byte{}, nil
+			}
+			return len(data), nil, nil
+		},
+	} {
+		items := []interface{}{}
+		rxgo.FromScanner(strings.NewReader("a b"), split).Subscribe(rxgo.ObserverMonitor{
+			Next: func(x interface{}) {
+				items = append(items, x)
+			},
+			Error: func(e error) {
+				items = append(items, e)
+			},
+		})
+		var oe *rxgo.OperatorError
+		if assert.True(t, len(items) > 0, "panic of split is not sent") {
+			assert.True(t, errors.As(items[len(items)-1].(error), &oe), "panic is not an OperatorError")
+		}
+	}
+}
+
+func TestFromReader(t *testing.T) {
+	res := []string{}
+	rxgo.FromReader(strings.NewReader("abcdefgh"), 3).Subscribe(func(x []byte) {
+		res = append(res, string(x))
+	})
+	assert.Equal(t, []string{"abc", "def", "gh"}, res, "chunks error")
+
+	ee := errors.New("broken")
+	var errs []error
+	rxgo.FromReader(io.MultiReader(strings.NewReader("abcd"), iotest.ErrReader(ee)), 3).Subscribe(rxgo.ObserverMonitor{
+		Error: func(e error) {
+			errs = append(errs, e)
+		},
+	})
+	assert.Equal(t, []error{ee}, errs, "read error")
+}
+
+func TestFromLinesWithCancel(t *testing.T) {
+	pr, pw, err := os.Pipe()
+	assert.NoError(t, err, "pipe error")
+	defer pw.Close()
+
+	received := make(chan string, 1)
+	s := rxgo.FromLines(pr).SubscribeAsync(func(x string) {
+		received <- x
+	})
+	pw.WriteString("hello\n")
+	assert.Equal(t, "hello", <-received, "line error")
+
+	// reading is blocked until the reader is closed by unsubscription
+	s.Dispose()
+	_, err = pr.Read(make([]byte, 1))
+	assert.Error(t, err, "reader not closed")
+}
+
+// subscribe TailFile and receive its lines from the channel
+func tailFile(path string, opts rxgo.TailOptions) (lines chan rxgo.TailLine, s *rxgo.Subscription) {
+	lines = make(chan rxgo.TailLine, 100)
+	opts.PollInterval = 5 * time.Millisecond
+	s = rxgo.TailFile(path, opts).SubscribeAsync(func(x rxgo.TailLine) {
+		lines <- x
+	})
+	return
+}
+
+func nextLines(t *testing.T, lines chan rxgo.TailLine, n int) []string {
+	res := []string{}
+	for len(res) < n {
+		select {
+		case l := <-lines:
+			res = append(res, l.Text)
+		case <-time.After(2 * time.Second):
+			t.Errorf("lines %v are received, want %d", res, n)
+			return res
+		}
+	}
+	return res
+}
+
+func appendFile(t *testing.T, path, text string) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	assert.NoError(t, err, "open file error")
+	f.WriteString(text)
+	f.Close()
+}
+
+func TestTailFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	lines, s := tailFile(path, rxgo.TailOptions{})
+	defer s.Dispose()
+
+	// wait for the file to be created
+	appendFile(t, path, "one\ntwo\nthr")
+	assert.Equal(t, []string{"one", "two"}, nextLines(t, lines, 2), "appended lines error")
+	appendFile(t, path, "ee\n")
+	assert.Equal(t, []string{"three"}, nextLines(t, lines, 1), "partial line error")
+
+	// rotation
+	appendFile(t, path, "four\n")
+	assert.NoError(t, os.Rename(path, path+".1"), "rotate error")
+	appendFile(t, path+".1", "five\n")
+	appendFile(t, path, "six\n")
+	assert.Equal(t, []string{"four", "five", "six"}, nextLines(t, lines, 3), "rotated lines error")
+
+	// truncation
+	assert.NoError(t, os.Truncate(path, 0), "truncate error")
+	appendFile(t, path, "7\n")
+	assert.Equal(t, []string{"7"}, nextLines(t, lines, 1), "truncated lines error")
+}
+
+func TestTailFileOffset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, path, "one\ntwo\n")
+
+	lines, s := tailFile(path, rxgo.TailOptions{})
+	l := <-lines
+	assert.Equal(t, rxgo.TailLine{Text: "one", Offset: 4}, l, "line offset error")
+	s.Dispose()
+
+	lines, s = tailFile(path, rxgo.TailOptions{Offset: l.Offset})
+	assert.Equal(t, []string{"two"}, nextLines(t, lines, 1), "resumed lines error")
+	s.Dispose()
+
+	lines, s = tailFile(path, rxgo.TailOptions{FromEnd: true})
+	defer s.Dispose()
+	time.Sleep(50 * time.Millisecond) // wait for the file opened
+	appendFile(t, path, "three\n")
+	assert.Equal(t, []string{"three"}, nextLines(t, lines, 1), "lines from end error")
+}
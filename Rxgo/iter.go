@@ -0,0 +1,59 @@
+// Copyright 2018 The SS.SYSU Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.23
+
+package rxgo
+
+import (
+	"context"
+	"iter"
+)
+
+// All returns an iterator of items and the error like Chan. Breaking the loop unsubscribes.
+//
+//	for x, err := range ob.All(ctx) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+func (o *Observable) All(ctx context.Context) iter.Seq2[interface{}, error] {
+	return func(yield func(interface{}, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		items, errs := o.Chan(ctx)
+		// wait until the subscription is completed when returned
+		defer func() {
+			for range items {
+			}
+			<-errs
+		}()
+
+		for x := range items {
+			if !yield(x, nil) {
+				cancel()
+				return
+			}
+		}
+		if e := <-errs; e != nil {
+			yield(nil, e)
+		}
+	}
+}
+
+// FromSeq creates an Observable of items of seq
+func FromSeq[T any](seq iter.Seq[T]) *Observable {
+	o := newGeneratorObservable("FromSeq")
+
+	o.flip = func(ctx context.Context, out *flow) {
+		for x := range seq {
+			if b := o.sendToFlow(ctx, x, out); b {
+				return
+			}
+		}
+	}
+	o.operator = flipSource
+	return o
+}
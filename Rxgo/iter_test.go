@@ -0,0 +1,60 @@
+//go:build go1.23
+
+package rxgo_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/pmlpml/rxgo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAll(t *testing.T) {
+	res := []interface{}{}
+	for x, err := range rxgo.Range(0, 5).All(context.Background()) {
+		assert.NoError(t, err, "iterate error")
+		res = append(res, x)
+		if x == 2 {
+			break
+		}
+	}
+	assert.Equal(t, []interface{}{0, 1, 2}, res, "iterated items error")
+
+	ee := errors.New("any")
+	var errs []error
+	for _, err := range rxgo.Just(1, ee, 2).All(context.Background()) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	assert.Equal(t, []error{ee}, errs, "iterated error")
+}
+
+func TestFromSeq(t *testing.T) {
+	res := []int{}
+	rxgo.FromSeq(slices.Values([]int{1, 2, 3})).Map(func(x int) int {
+		return 2 * x
+	}).Subscribe(func(x int) {
+		res = append(res, x)
+	})
+	assert.Equal(t, []int{2, 4, 6}, res, "seq items error")
+}
+
+func TestFromSeqPanic(t *testing.T) {
+	res := []interface{}{}
+	rxgo.FromSeq(func(yield func(int) bool) {
+		yield(1)
+		panic("boom")
+	}).Subscribe(collectItems(&res))
+	if assert.Equal(t, 2, len(res), "items of panicking seq") {
+		assert.Equal(t, 1, res[0], "item before panic error")
+		var oe *rxgo.OperatorError
+		if assert.True(t, errors.As(res[1].(error), &oe), "panic is not an OperatorError") {
+			assert.Equal(t, "boom", oe.Value, "panic value error")
+			assert.Equal(t, "FromSeq", oe.Stage, "stage error")
+		}
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2018 The SS.SYSU Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rxgo
+
+import (
+	"bytes"
+	"reflect"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// TB is the part of testing.TB used by VerifyNoLeaks
+type TB interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// time to wait for goroutines exiting in VerifyNoLeaks
+var LeakCheckTimeout = time.Second
+
+// VerifyNoLeaks runs f, such as subscribing a pipeline, and reports an error to t
+// if any goroutine running code of this package is started by f and still running after f returns.
+func VerifyNoLeaks(t TB, f func()) {
+	t.Helper()
+	before := pipelineGoroutines()
+	f()
+
+	var leaked []string
+	deadline := time.Now().Add(LeakCheckTimeout)
+	for {
+		leaked = leaked[:0]
+		for id, stack := range pipelineGoroutines() {
+			if _, ok := before[id]; !ok {
+				leaked = append(leaked, stack)
+			}
+		}
+		if len(leaked) == 0 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if len(leaked) > 0 {
+		t.Errorf("%d goroutines of pipelines leaked:\n\n%s", len(leaked), strings.Join(leaked, "\n\n"))
+	}
+}
+
+var pkgPrefix = reflect.TypeOf(Observable{}).PkgPath() + "."
+
+// get stacks of goroutines running code of this package by goroutine id, except the current one
+func pipelineGoroutines() map[string]string {
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	res := make(map[string]string)
+	for i, g := range bytes.Split(buf, []byte("\n\n")) {
+		if i == 0 {
+			continue // current goroutine
+		}
+		stack := string(g)
+		header := strings.SplitN(stack, "\n", 2)[0] // goroutine 18 [chan receive]:
+		fields := strings.Fields(header)
+		if len(fields) < 2 {
+			continue
+		}
+		if strings.Contains(stack, "\n"+pkgPrefix) {
+			res[fields[1]] = stack
+		}
+	}
+	return res
+}
@@ -0,0 +1,116 @@
+package rxgo_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pmlpml/rxgo"
+	"github.com/stretchr/testify/assert"
+)
+
+// infinite source
+func naturals() *rxgo.Observable {
+	i := 0
+	return rxgo.Start(func() (int, bool) {
+		i++
+		return i, false
+	})
+}
+
+type recorder struct {
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestVerifyNoLeaksReports(t *testing.T) {
+	r := &recorder{}
+	stop := make(chan struct{})
+	rxgo.VerifyNoLeaks(r, func() {
+		rxgo.Generator(func(ctx context.Context, send func(x interface{}) (endSignal bool)) {
+			<-stop
+		}).SubscribeAsync(func(x int) {})
+	})
+	close(stop)
+	assert.Equal(t, 1, len(r.errors), "leak not reported")
+}
+
+func TestFirstOfInfinite(t *testing.T) {
+	rxgo.VerifyNoLeaks(t, func() {
+		res := []int{}
+		naturals().Map(func(x int) int {
+			return x
+		}).First().Subscribe(func(x int) {
+			res = append(res, x)
+		})
+		assert.Equal(t, []int{1}, res, "First error")
+	})
+}
+
+func TestEoFlowOfInfinite(t *testing.T) {
+	rxgo.VerifyNoLeaks(t, func() {
+		res := []int{}
+		naturals().Map(func(x int) int {
+			if x > 3 {
+				panic(rxgo.ErrEoFlow)
+			}
+			return x
+		}).Subscribe(func(x int) {
+			res = append(res, x)
+		})
+		assert.Equal(t, []int{1, 2, 3}, res, "EoFlow error")
+	})
+}
+
+func TestFlatMapInnerTeardown(t *testing.T) {
+	rxgo.VerifyNoLeaks(t, func() {
+		count := 0
+		var observer = rxgo.ObserverMonitor{}
+		observer.Context = func() context.Context {
+			ctx, cancel := context.WithCancel(context.Background())
+			observer.CancelObservables = cancel
+			return ctx
+		}
+		observer.Next = func(x interface{}) {
+			count++
+			if count == 5 {
+				observer.Unsubscribe()
+			}
+		}
+		rxgo.Just(1, 2, 3).FlatMap(func(x int) *rxgo.Observable {
+			return naturals()
+		}).SubscribeOn(rxgo.ThreadingIO).Subscribe(observer)
+		assert.True(t, count >= 5, "FlatMap items error")
+	})
+}
+
+func TestSubscriptionDispose(t *testing.T) {
+	rxgo.VerifyNoLeaks(t, func() {
+		s := naturals().Map(func(x int) int {
+			return x
+		}).SubscribeOn(rxgo.ThreadingComputing).SubscribeAsync(func(x int) {})
+		time.Sleep(time.Millisecond)
+		s.Dispose()
+
+		select {
+		case <-s.Done():
+		default:
+			t.Error("subscription is not done after Dispose")
+		}
+	})
+
+	rxgo.VerifyNoLeaks(t, func() {
+		res := []int{}
+		s := rxgo.Just(1, 2, 3).SubscribeAsync(func(x int) {
+			res = append(res, x)
+		})
+		s.Wait()
+		assert.Equal(t, []int{1, 2, 3}, res, "SubscribeAsync error")
+	})
+}
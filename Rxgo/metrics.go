@@ -0,0 +1,216 @@
+// Copyright 2018 The SS.SYSU Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rxgo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics records runtime events of stages keyed by the stage name.
+// It is called by many goroutines at the same time.
+type Metrics interface {
+	ItemSent(stage string, wait time.Duration)          // an item is sent to next stage, wait is the time blocked on sending
+	ErrorSent(stage string)                             // an error is sent to next stage
+	ItemProcessed(stage string, duration time.Duration) // the user function of stage is called with an item for duration
+	GoroutineStarted(stage string)
+	GoroutineStopped(stage string)
+}
+
+// WithMetrics sets a Metrics to record all stages of the chain
+func WithMetrics(m Metrics) Option {
+	return func(op *Options) {
+		op.Metrics = m
+	}
+}
+
+// get metrics of the chain connected with ctx, nil if not configured
+func metricsOf(ctx context.Context) Metrics {
+	if op := optionsOf(ctx); op != nil {
+		return op.Metrics
+	}
+	return nil
+}
+
+// get metrics of the chain of the flow, nil if not configured
+func (fl *flow) metrics() Metrics {
+	if fl.options != nil {
+		return fl.options.Metrics
+	}
+	return nil
+}
+
+func (o *Observable) goroutineStarted(ctx context.Context) {
+	if m := metricsOf(ctx); m != nil {
+		m.GoroutineStarted(o.stageName(optionsOf(ctx)))
+	}
+}
+
+func (o *Observable) goroutineStopped(ctx context.Context) {
+	if m := metricsOf(ctx); m != nil {
+		m.GoroutineStopped(o.stageName(optionsOf(ctx)))
+	}
+}
+
+// default latency buckets in seconds
+var DefaultLatencyBuckets = []float64{0.000001, 0.00001, 0.0001, 0.001, 0.01, 0.1, 1}
+
+// PromMetrics is a Metrics which is exported in Prometheus text format when served as http.Handler
+type PromMetrics struct {
+	mu      sync.Mutex
+	buckets []float64
+	stages  map[string]*stageMetrics
+}
+
+type stageMetrics struct {
+	items      uint64
+	errors     uint64
+	goroutines int64
+	wait       histogram // time blocked on sending items
+	process    histogram // time of user function per item
+}
+
+// histogram of durations in buckets of PromMetrics
+type histogram struct {
+	counts []uint64 // count in each bucket, last one is +Inf
+	sum    float64
+}
+
+func (h *histogram) observe(buckets []float64, d time.Duration) {
+	sec := d.Seconds()
+	h.sum += sec
+	h.counts[sort.SearchFloat64s(buckets, sec)]++
+}
+
+var _ Metrics = (*PromMetrics)(nil)
+var _ http.Handler = (*PromMetrics)(nil)
+
+// NewPromMetrics creates a PromMetrics with latency buckets in seconds. DefaultLatencyBuckets is used if none is given.
+// The send wait of items measures the backpressure of next stage, and the process time measures user functions.
+func NewPromMetrics(buckets ...float64) *PromMetrics {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	b := append([]float64(nil), buckets...)
+	sort.Float64s(b)
+	return &PromMetrics{buckets: b, stages: make(map[string]*stageMetrics)}
+}
+
+// get stage metrics, must hold lock
+func (m *PromMetrics) stage(name string) *stageMetrics {
+	s, ok := m.stages[name]
+	if !ok {
+		s = &stageMetrics{
+			wait:    histogram{counts: make([]uint64, len(m.buckets)+1)},
+			process: histogram{counts: make([]uint64, len(m.buckets)+1)},
+		}
+		m.stages[name] = s
+	}
+	return s
+}
+
+func (m *PromMetrics) ItemSent(stage string, wait time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.stage(stage)
+	s.items++
+	s.wait.observe(m.buckets, wait)
+}
+
+func (m *PromMetrics) ErrorSent(stage string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stage(stage).errors++
+}
+
+func (m *PromMetrics) ItemProcessed(stage string, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stage(stage).process.observe(m.buckets, duration)
+}
+
+func (m *PromMetrics) GoroutineStarted(stage string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stage(stage).goroutines++
+}
+
+func (m *PromMetrics) GoroutineStopped(stage string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stage(stage).goroutines--
+}
+
+// WriteTo writes all metrics in Prometheus text format
+func (m *PromMetrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.stages))
+	for name := range m.stages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("# HELP rxgo_stage_items_total Items sent by stage.\n")
+	b.WriteString("# TYPE rxgo_stage_items_total counter\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "rxgo_stage_items_total{stage=%s} %d\n", quoteLabel(name), m.stages[name].items)
+	}
+	b.WriteString("# HELP rxgo_stage_errors_total Errors sent by stage.\n")
+	b.WriteString("# TYPE rxgo_stage_errors_total counter\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "rxgo_stage_errors_total{stage=%s} %d\n", quoteLabel(name), m.stages[name].errors)
+	}
+	b.WriteString("# HELP rxgo_stage_goroutines Goroutines running in stage.\n")
+	b.WriteString("# TYPE rxgo_stage_goroutines gauge\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "rxgo_stage_goroutines{stage=%s} %d\n", quoteLabel(name), m.stages[name].goroutines)
+	}
+	m.writeHistogram(&b, names, "rxgo_stage_send_wait_seconds", "Time blocked on sending an item to next stage by backpressure.",
+		func(s *stageMetrics) *histogram { return &s.wait })
+	m.writeHistogram(&b, names, "rxgo_stage_process_seconds", "Time of the user function of stage per item.",
+		func(s *stageMetrics) *histogram { return &s.process })
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// write a histogram of stages, must hold lock
+func (m *PromMetrics) writeHistogram(b *strings.Builder, names []string, name, help string, of func(s *stageMetrics) *histogram) {
+	fmt.Fprintf(b, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(b, "# TYPE %s histogram\n", name)
+	for _, stage := range names {
+		h, label := of(m.stages[stage]), quoteLabel(stage)
+		var acc uint64
+		for i, le := range m.buckets {
+			acc += h.counts[i]
+			fmt.Fprintf(b, "%s_bucket{stage=%s,le=\"%s\"} %d\n", name, label, strconv.FormatFloat(le, 'g', -1, 64), acc)
+		}
+		acc += h.counts[len(m.buckets)]
+		fmt.Fprintf(b, "%s_bucket{stage=%s,le=\"+Inf\"} %d\n", name, label, acc)
+		fmt.Fprintf(b, "%s_sum{stage=%s} %s\n", name, label, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(b, "%s_count{stage=%s} %d\n", name, label, acc)
+	}
+}
+
+// ServeHTTP serves metrics in Prometheus text format, such as `http.Handle("/metrics", m)`
+func (m *PromMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+func quoteLabel(v string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + r.Replace(v) + `"`
+}
@@ -0,0 +1,93 @@
+package rxgo_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pmlpml/rxgo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPromMetrics(t *testing.T) {
+	m := rxgo.NewPromMetrics()
+	rxgo.Just(1, 2, errors.New("any"), 3).Map(func(x int) int {
+		return 2 * x
+	}).Configure(rxgo.WithMetrics(m)).Subscribe(func(x int) {})
+
+	srv := httptest.NewServer(m)
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/metrics")
+	assert.NoError(t, err, "get metrics error")
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	text := string(body)
+
+	assert.True(t, strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain"), "content type error")
+	for _, line := range []string{
+		`rxgo_stage_items_total{stage="Just"} 3`,
+		`rxgo_stage_items_total{stage="map"} 3`,
+		`rxgo_stage_errors_total{stage="Just"} 1`,
+		`rxgo_stage_errors_total{stage="map"} 1`,
+		`rxgo_stage_goroutines{stage="map"} 0`,
+		`rxgo_stage_send_wait_seconds_bucket{stage="map",le="+Inf"} 3`,
+		`rxgo_stage_send_wait_seconds_count{stage="map"} 3`,
+		`rxgo_stage_process_seconds_count{stage="map"} 3`,
+	} {
+		assert.Contains(t, text, line, "metrics line missed")
+	}
+}
+
+func TestPromMetricsProcess(t *testing.T) {
+	m := rxgo.NewPromMetrics(0.005)
+	// the map stage is fused with the filter one, so its send wait is about 0
+	rxgo.Range(0, 2).Map(func(x int) int {
+		time.Sleep(10 * time.Millisecond)
+		return x
+	}).Filter(func(x int) bool {
+		return true
+	}).Configure(rxgo.WithMetrics(m)).Subscribe(func(x int) {})
+
+	var b strings.Builder
+	m.WriteTo(&b)
+	text := b.String()
+	for _, line := range []string{
+		`rxgo_stage_process_seconds_bucket{stage="map",le="0.005"} 0`,
+		`rxgo_stage_process_seconds_count{stage="map"} 2`,
+		`rxgo_stage_process_seconds_bucket{stage="filter",le="0.005"} 2`,
+	} {
+		assert.Contains(t, text, line, "metrics line missed")
+	}
+}
+
+func TestPromMetricsGoroutines(t *testing.T) {
+	m := rxgo.NewPromMetrics()
+	goroutines := func() int {
+		var b strings.Builder
+		m.WriteTo(&b)
+		r := regexp.MustCompile(`rxgo_stage_goroutines\{stage="io/map"\} (-?\d+)`).FindStringSubmatch(b.String())
+		if r == nil {
+			return -1
+		}
+		n, _ := strconv.Atoi(r[1])
+		return n
+	}
+	running := -1
+	// the stage can not complete before the first item is received, since its buffer is smaller than the items
+	ob := rxgo.Range(0, 1000).Map(func(x int) int {
+		return x
+	}).SubscribeOn(rxgo.ThreadingIO).Configure(rxgo.WithMetrics(m), rxgo.WithName("io"))
+	ob.Subscribe(func(x int) {
+		if running < 0 {
+			running = goroutines()
+		}
+	})
+	assert.True(t, running > 0, "running goroutines missed")
+	assert.Equal(t, 0, goroutines(), "goroutines not stopped")
+}
@@ -0,0 +1,215 @@
+// Copyright 2018 The SS.SYSU Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rxgo
+
+import (
+	"context"
+	"time"
+)
+
+// Options of a chain of Observables. They are held by the Observable they are configured on
+// and applied to every stage of the chain ending at the subscribed Observable when it is connected.
+type Options struct {
+	Name            string          // name of the chain, prefixed to stage names in Stats
+	BufferLen       uint            // buffer of operator stages
+	SourceBufferLen uint            // buffer of the source stage
+	StageBufferLen  map[string]uint // buffer of stages by name, overrides BufferLen and SourceBufferLen
+	Threading       ThreadModel     // threading model of operator stages without SubscribeOn
+	Metrics         Metrics         // records runtime events of stages
+	Tracing         SpanExporter    // exports spans of items through stages
+	CrashOnPanic    bool            // do not recover panics of user functions as OperatorError
+	Coercion        Coercion        // policy to pass items to parameter of user functions
+	Fusion          bool            // fuse consecutive operator stages with ThreadingDefault into one goroutine, default true
+	BatchSize       int             // max items sent between stages at once, batching is disabled if less than 2
+	BatchLatency    time.Duration   // max time an item waits in a batch
+}
+
+// Option sets a field of Options
+type Option func(*Options)
+
+// NewOptions creates chain options with defaults and then applies opts on them
+func NewOptions(opts ...Option) *Options {
+	op := &Options{
+		BufferLen:       BufferLen,
+		SourceBufferLen: 0,
+		Threading:       ThreadingDefault,
+		Fusion:          true,
+	}
+	for _, opt := range opts {
+		opt(op)
+	}
+	return op
+}
+
+// WithName names the chain
+func WithName(name string) Option {
+	return func(op *Options) {
+		op.Name = name
+	}
+}
+
+// WithBufferLen sets buffer length of all operator stages
+func WithBufferLen(length uint) Option {
+	return func(op *Options) {
+		op.BufferLen = length
+	}
+}
+
+// WithSourceBufferLen sets buffer length of the source stage
+func WithSourceBufferLen(length uint) Option {
+	return func(op *Options) {
+		op.SourceBufferLen = length
+	}
+}
+
+// WithStageBufferLen sets buffer length of the stages with the name, such as "map" or "filter"
+func WithStageBufferLen(name string, length uint) Option {
+	return func(op *Options) {
+		if op.StageBufferLen == nil {
+			op.StageBufferLen = make(map[string]uint)
+		}
+		op.StageBufferLen[name] = length
+	}
+}
+
+// WithThreading sets threading model of operator stages which are not set by SubscribeOn
+func WithThreading(t ThreadModel) Option {
+	return func(op *Options) {
+		op.Threading = t
+	}
+}
+
+// WithCrashOnPanic lets panics of user functions crash the process instead of flowing as OperatorError
+func WithCrashOnPanic(crash bool) Option {
+	return func(op *Options) {
+		op.CrashOnPanic = crash
+	}
+}
+
+// WithCoercion sets the policy to pass items to parameter of user functions
+func WithCoercion(c Coercion) Option {
+	return func(op *Options) {
+		op.Coercion = c
+	}
+}
+
+// WithFusion fuses consecutive operator stages with ThreadingDefault, such as Map, Filter and Take,
+// into one goroutine without channels between them, which is the default. Items and errors flow as they do
+// through channels, but a fused stage does not run ahead of the next one. A stage with a buffer set by SetBufferLen,
+// or by options other than the default BufferLen, is not fused with the next one.
+func WithFusion(fusion bool) Option {
+	return func(op *Options) {
+		op.Fusion = fusion
+	}
+}
+
+// Configure applies options to the chain ending at this Observable, and to chains built from it later.
+// Stages before it may be shared by other chains, so they are not changed. Options of a chain are
+// those configured on its last Observable or on the nearest stage before it, and options that were
+// configured before are kept unless they are set again.
+func (o *Observable) Configure(opts ...Option) *Observable {
+	op := NewOptions()
+	if cur := o.chainOptions(); cur != nil {
+		op = cur.clone()
+	}
+	for _, opt := range opts {
+		opt(op)
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.options = op
+	return o
+}
+
+// SetOptions replaces options of the chain ending at this Observable, like Configure
+func (o *Observable) SetOptions(op *Options) *Observable {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.options = op
+	return o
+}
+
+// copy of options
+func (op *Options) clone() *Options {
+	c := *op
+	if op.StageBufferLen != nil {
+		c.StageBufferLen = make(map[string]uint, len(op.StageBufferLen))
+		for k, v := range op.StageBufferLen {
+			c.StageBufferLen[k] = v
+		}
+	}
+	return &c
+}
+
+// get options of the chain ending at this Observable, nil if not configured
+func (o *Observable) chainOptions() *Options {
+	for po := o; po != nil; po = po.pred {
+		po.mu.Lock()
+		op := po.options
+		po.mu.Unlock()
+		if op != nil {
+			return op
+		}
+	}
+	return nil
+}
+
+type chainOptionsKey struct{}
+
+// options of the chain connected with ctx, nil if not configured
+func optionsOf(ctx context.Context) *Options {
+	op, _ := ctx.Value(chainOptionsKey{}).(*Options)
+	return op
+}
+
+// buffer length of this stage in a chain of options op
+func (o *Observable) stageBufferLen(op *Options) uint {
+	if o.buf_set || op == nil {
+		return o.buf_len
+	}
+	if l, ok := op.StageBufferLen[o.Name]; ok {
+		return l
+	}
+	if o.pred == nil {
+		return op.SourceBufferLen
+	}
+	return op.BufferLen
+}
+
+// threading model of this stage in a chain of options op
+func (o *Observable) stageThreading(op *Options) ThreadModel {
+	if o.threading_set || op == nil {
+		return o.threading
+	}
+	return op.Threading
+}
+
+// can this stage be fused with its neighbours in a chain of options op
+func (o *Observable) fusable(op *Options) bool {
+	if op != nil && !op.Fusion {
+		return false
+	}
+	switch o.operator.(type) {
+	case transOperater, filterOperator:
+		return o.stageThreading(op) == ThreadingDefault
+	}
+	return false
+}
+
+// coercion policy of this stage in a chain of options op
+func (o *Observable) stageCoercion(op *Options) Coercion {
+	if op != nil {
+		return op.Coercion
+	}
+	return CoercionAssignable
+}
+
+// name of this stage in a chain of options op, which is reported in Stats
+func (o *Observable) stageName(op *Options) string {
+	if op != nil && op.Name != "" {
+		return op.Name + "/" + o.Name
+	}
+	return o.Name
+}
@@ -0,0 +1,165 @@
+package rxgo_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pmlpml/rxgo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptionsBufferLen(t *testing.T) {
+	source := rxgo.Just(1, 2, 3)
+	ob := source.Map(func(x int) int {
+		return x
+	}).Filter(func(x int) bool {
+		return true
+	}).SetBufferLen(5)
+	ob.Configure(
+		rxgo.WithName("chain"),
+		rxgo.WithSourceBufferLen(2),
+		rxgo.WithBufferLen(7),
+	)
+
+	var stats []rxgo.StageStats
+	ob.Subscribe(rxgo.ObserverMonitor{
+		Next: func(x interface{}) {
+			if stats == nil {
+				stats = ob.Stats()
+			}
+		},
+	})
+
+	names := []string{}
+	caps := []int{}
+	for _, s := range stats {
+		names = append(names, s.Name)
+		caps = append(caps, s.Cap)
+	}
+	assert.Equal(t, []string{"chain/Just", "chain/map", "chain/filter"}, names, "Stats name error")
+	assert.Equal(t, []int{2, 7, 5}, caps, "Stats cap error")
+
+	processed := []uint64{}
+	for _, s := range ob.Stats() {
+		processed = append(processed, s.Processed)
+	}
+	assert.Equal(t, []uint64{3, 3, 3}, processed, "Stats processed error")
+}
+
+func TestOptionsStageBufferLen(t *testing.T) {
+	ob := rxgo.Range(0, 3).Map(func(x int) int {
+		return x
+	}).Configure(rxgo.WithStageBufferLen("map", 1), rxgo.WithStageBufferLen("Range", 3))
+
+	var stats []rxgo.StageStats
+	ob.Subscribe(rxgo.ObserverMonitor{
+		Next: func(x interface{}) {
+			stats = ob.Stats()
+		},
+	})
+	assert.Equal(t, 3, stats[0].Cap, "source buffer error")
+	assert.Equal(t, 1, stats[1].Cap, "stage buffer error")
+}
+
+func TestOptionsThreading(t *testing.T) {
+	res := 0
+	rxgo.Just(1, 2, 3).Map(func(x int) int {
+		return 2 * x
+	}).SetOptions(rxgo.NewOptions(rxgo.WithThreading(rxgo.ThreadingIO))).Subscribe(func(x int) {
+		res += x
+	})
+	assert.Equal(t, 12, res, "threading option error")
+}
+
+func fusionChain(fusion bool) *rxgo.Observable {
+	return rxgo.Range(0, 20).Map(func(x int) int {
+		return 2 * x
+	}).Filter(func(x int) bool {
+		return x%4 == 0
+	}).SetBufferLen(5).Map(func(x int) int {
+		return x + 1
+	}).Take(3).Configure(rxgo.WithFusion(fusion))
+}
+
+func TestOptionsFusion(t *testing.T) {
+	expected := []int{}
+	fusionChain(false).Subscribe(func(x int) {
+		expected = append(expected, x)
+	})
+
+	res := []int{}
+	var stats []rxgo.StageStats
+	ob := fusionChain(true)
+	ob.Subscribe(rxgo.ObserverMonitor{
+		Next: func(x interface{}) {
+			res = append(res, x.(int))
+			stats = ob.Stats()
+		},
+	})
+	assert.Equal(t, []int{1, 5, 9}, expected, "chain items error")
+	assert.Equal(t, expected, res, "fused items error")
+
+	caps := []int{}
+	for _, s := range stats {
+		caps = append(caps, s.Cap)
+	}
+	assert.Equal(t, []int{0, 0, 5, 0, 128}, caps, "fused flows error")
+}
+
+func TestOptionsOfSiblings(t *testing.T) {
+	source := rxgo.Range(0, 3).Configure(rxgo.WithName("src"))
+	double := func(x int) int { return 2 * x }
+	a := source.Map(double).Configure(rxgo.WithName("a"), rxgo.WithBufferLen(3))
+	b := source.Map(double).Configure(rxgo.WithStageBufferLen("Range", 2))
+	c := source.Map(func(x int64) int64 { return x })
+
+	names := func(ob *rxgo.Observable) (res []string) {
+		for _, s := range ob.Stats() {
+			res = append(res, s.Name)
+		}
+		return
+	}
+	count := func(ob *rxgo.Observable) (items, errs int) {
+		ob.Subscribe(rxgo.ObserverMonitor{
+			Next:  func(x interface{}) { items++ },
+			Error: func(e error) { errs++ },
+		})
+		return
+	}
+
+	for _, ob := range []*rxgo.Observable{a, b, c, a} {
+		count(ob)
+	}
+	assert.Equal(t, []string{"a/Range", "a/map"}, names(a), "names of branch a error")
+	assert.Equal(t, []string{"src/Range", "src/map"}, names(b), "names of branch b error")
+	assert.Equal(t, []string{"src/Range", "src/map"}, names(c), "names of branch c error")
+	assert.Equal(t, 3, a.Stats()[1].Cap, "buffer of branch a error")
+	assert.Equal(t, 0, a.Stats()[0].Cap, "source buffer of branch a error")
+	assert.Equal(t, 2, b.Stats()[0].Cap, "source buffer of branch b error")
+	assert.Equal(t, int(rxgo.BufferLen), c.Stats()[1].Cap, "buffer of branch c error")
+
+	// int items are passed to int64 parameters only if the coercion is convertible
+	_, errs := count(c)
+	assert.Equal(t, 3, errs, "coercion of branch c error")
+	c.Configure(rxgo.WithCoercion(rxgo.CoercionConvertible))
+	_, errs = count(c)
+	assert.Equal(t, 0, errs, "coercion of configured branch c error")
+	_, errs = count(source.Map(func(x int64) int64 { return x }))
+	assert.Equal(t, 3, errs, "configuring a branch changes the source")
+}
+
+func BenchmarkChain(b *testing.B) {
+	for _, fusion := range []bool{false, true} {
+		b.Run(fmt.Sprintf("fusion=%v", fusion), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				ob := rxgo.Range(0, 10000)
+				for j := 0; j < 10; j++ {
+					ob = ob.Map(func(x int) int {
+						return x + 1
+					})
+				}
+				ob.Configure(rxgo.WithFusion(fusion)).Subscribe(func(x int) {})
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2018 The SS.SYSU Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rxgo
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sync"
+)
+
+// FromSignals creates an Observable of os.Signal received until unsubscribed, all incoming signals if sigs is empty.
+// Each subscription is notified of the signals independently.
+func FromSignals(sigs ...os.Signal) *Observable {
+	o := newGeneratorObservable("FromSignals")
+
+	o.flip = func(ctx context.Context, out *flow) {
+		ch := make(chan os.Signal, 1)
+		signal.Notify(ch, sigs...)
+		defer signal.Stop(ch)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case sig := <-ch:
+				if b := o.sendToFlow(ctx, sig, out); b {
+					return
+				}
+			}
+		}
+	}
+	o.operator = flipSource
+	return o
+}
+
+// CommandStream is the output stream of a CommandLine
+type CommandStream int
+
+const (
+	CommandStdout CommandStream = iota
+	CommandStderr
+)
+
+func (s CommandStream) String() string {
+	switch s {
+	case CommandStdout:
+		return "stdout"
+	case CommandStderr:
+		return "stderr"
+	}
+	return "unknown"
+}
+
+// CommandLine is a line written by the command run by FromCommand
+type CommandLine struct {
+	Stream CommandStream
+	Text   string // line without end-of-line markers
+}
+
+// CommandOptions of FromCommand
+type CommandOptions struct {
+	MaxLineSize int // max size of a line, bufio.MaxScanTokenSize if not positive
+}
+
+// FromCommand creates an Observable of CommandLine written to stdout and stderr by cmd, which is started on subscription.
+// It completes if cmd exits successfully, otherwise the error of cmd, such as *exec.ExitError, is sent before completion.
+// A read error of a stream, such as bufio.ErrTooLong, is sent and the rest of the stream is discarded.
+// cmd is killed on unsubscription. cmd can be run only once, so the Observable is subscribed once.
+func FromCommand(cmd *exec.Cmd, opts CommandOptions) *Observable {
+	o := newGeneratorObservable("FromCommand")
+
+	o.flip = func(ctx context.Context, out *flow) {
+		stdout, e := cmd.StdoutPipe()
+		if e != nil {
+			o.sendToFlow(ctx, e, out)
+			return
+		}
+		stderr, e := cmd.StderrPipe()
+		if e != nil {
+			o.sendToFlow(ctx, e, out)
+			return
+		}
+		if e = cmd.Start(); e != nil {
+			o.sendToFlow(ctx, e, out)
+			return
+		}
+
+		// the command is killed on unsubscription, which ends reading of its streams
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			select {
+			case <-ctx.Done():
+				cmd.Process.Kill()
+			case <-done:
+			}
+		}()
+
+		// lines and errors of both streams are merged, so they are sent to the flow in one goroutine
+		lines := make(chan interface{})
+		var wg sync.WaitGroup
+		read := func(r io.Reader, s CommandStream) {
+			defer wg.Done()
+			sc := bufio.NewScanner(r)
+			if opts.MaxLineSize > 0 {
+				sc.Buffer(nil, opts.MaxLineSize)
+			}
+			for sc.Scan() {
+				select {
+				case lines <- CommandLine{Stream: s, Text: sc.Text()}:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if e := sc.Err(); e != nil {
+				select {
+				case lines <- e:
+				case <-ctx.Done():
+					return
+				}
+				// the command would block on writing if the stream is not read
+				io.Copy(io.Discard, r)
+			}
+		}
+		wg.Add(2)
+		go read(stdout, CommandStdout)
+		go read(stderr, CommandStderr)
+		go func() {
+			wg.Wait()
+			close(lines)
+		}()
+
+		stopped := false
+		for l := range lines {
+			if !stopped && o.sendToFlow(ctx, l, out) {
+				// drain lines until the killed command closes its streams
+				stopped = true
+				cmd.Process.Kill()
+			}
+		}
+		if e = cmd.Wait(); e != nil && !stopped && ctx.Err() == nil {
+			o.sendToFlow(ctx, e, out)
+		}
+	}
+	o.operator = flipSource
+	return o
+}
@@ -0,0 +1,141 @@
+package rxgo_test
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/pmlpml/rxgo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromSignals(t *testing.T) {
+	// ignore SIGHUP before the subscription is notified
+	guard := make(chan os.Signal, 1)
+	signal.Notify(guard, syscall.SIGHUP)
+	defer signal.Stop(guard)
+
+	got := make(chan os.Signal, 1)
+	sub := rxgo.FromSignals(syscall.SIGHUP).SubscribeAsync(func(sig os.Signal) {
+		select {
+		case got <- sig:
+		default:
+		}
+	})
+	defer sub.Dispose()
+
+	p, _ := os.FindProcess(os.Getpid())
+	tick := time.NewTicker(10 * time.Millisecond)
+	defer tick.Stop()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case sig := <-got:
+			assert.Equal(t, syscall.SIGHUP, sig, "received signal error")
+			return
+		case <-tick.C:
+			p.Signal(syscall.SIGHUP)
+		case <-timeout:
+			t.Fatal("signal is not received")
+		}
+	}
+}
+
+func TestFromCommand(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not found")
+	}
+	stdout, stderr := []string{}, []string{}
+	var errs []error
+	rxgo.FromCommand(exec.Command("sh", "-c", "echo a; echo b >&2; echo c"), rxgo.CommandOptions{}).SubscribeFuncs(func(l rxgo.CommandLine) {
+		if l.Stream == rxgo.CommandStdout {
+			stdout = append(stdout, l.Text)
+		} else {
+			stderr = append(stderr, l.Text)
+		}
+	}, func(e error) { errs = append(errs, e) }, nil)
+	assert.Equal(t, "a,c", strings.Join(stdout, ","), "stdout lines error")
+	assert.Equal(t, "b", strings.Join(stderr, ","), "stderr lines error")
+	assert.Equal(t, 0, len(errs), "exit status error")
+
+	res := []interface{}{}
+	rxgo.FromCommand(exec.Command("sh", "-c", "echo x; exit 3"), rxgo.CommandOptions{}).Subscribe(collectItems(&res))
+	if assert.Equal(t, 2, len(res), "exit status error") {
+		assert.Equal(t, rxgo.CommandLine{Stream: rxgo.CommandStdout, Text: "x"}, res[0], "line error")
+		var ee *exec.ExitError
+		if assert.True(t, errors.As(res[1].(error), &ee), "exit error") {
+			assert.Equal(t, 3, ee.ExitCode(), "exit code error")
+		}
+	}
+
+	res = res[:0]
+	rxgo.FromCommand(exec.Command("no-such-command-of-rxgo"), rxgo.CommandOptions{}).Subscribe(collectItems(&res))
+	assert.Equal(t, 1, len(res), "start error")
+}
+
+func TestFromCommandLongLine(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not found")
+	}
+	script := "head -c 200000 /dev/zero | tr '\\0' a; echo; echo end >&2"
+	for _, size := range []int{0, 1 << 20} {
+		var lens []int
+		var errs []error
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			rxgo.FromCommand(exec.Command("sh", "-c", script), rxgo.CommandOptions{MaxLineSize: size}).SubscribeFuncs(func(l rxgo.CommandLine) {
+				lens = append(lens, len(l.Text))
+			}, func(e error) { errs = append(errs, e) }, nil)
+		}()
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+			t.Fatal("command blocks on a long line")
+		}
+		if size == 0 {
+			assert.Equal(t, []int{3}, lens, "lines after a long line error")
+			assert.Equal(t, []error{bufio.ErrTooLong}, errs, "long line error")
+		} else {
+			assert.Equal(t, 2, len(lens), "long line is not read")
+			assert.Equal(t, 0, len(errs), "errors of long line")
+		}
+	}
+}
+
+func TestFromCommandUnsubscribe(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not found")
+	}
+	cmd := exec.Command("sh", "-c", "while true; do echo x; sleep 0.01; done")
+	first := make(chan struct{})
+	sub := rxgo.FromCommand(cmd, rxgo.CommandOptions{}).SubscribeAsync(func(l rxgo.CommandLine) {
+		select {
+		case first <- struct{}{}:
+		default:
+		}
+	})
+	select {
+	case <-first:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no output of the command")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		sub.Dispose()
+		close(done)
+	}()
+	select {
+	case <-done:
+		assert.True(t, cmd.ProcessState != nil, "command is not waited")
+	case <-time.After(5 * time.Second):
+		t.Error("command is not killed on unsubscription")
+	}
+}
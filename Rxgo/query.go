@@ -0,0 +1,220 @@
+// Copyright 2018 The SS.SYSU Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rxgo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+)
+
+// FromQuery creates an Observable of rows of the query as map[string]interface{} by column names, which are
+// emitted as they are read. The rows are closed on completion, unsubscription or ctx done, and a query error
+// is sent before completion. ctx can be cancelled independently of unsubscription.
+func FromQuery(ctx context.Context, db *sql.DB, query string, args ...interface{}) *Observable {
+	return newQueryObservable(ctx, "FromQuery", db, query, args, nil)
+}
+
+// FromQueryStruct creates an Observable of rows of the query as values of the type of proto, a struct or pointer
+// to struct whose fields are matched to columns by `db` tags or names ignoring case. Unmatched columns are dropped.
+// Others are the same as FromQuery.
+func FromQueryStruct(ctx context.Context, db *sql.DB, proto interface{}, query string, args ...interface{}) *Observable {
+	sf := newStructFields(reflect.TypeOf(proto), "db")
+	if sf == nil {
+		panic(ErrFuncFlip)
+	}
+	return newQueryObservable(ctx, "FromQueryStruct", db, query, args, sf)
+}
+
+func newQueryObservable(qctx context.Context, name string, db *sql.DB, query string, args []interface{}, proto *structFields) *Observable {
+	o := newGeneratorObservable(name)
+
+	o.flip = func(ctx context.Context, out *flow) {
+		// the query is cancelled on unsubscription or qctx done
+		rctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go func() {
+			select {
+			case <-qctx.Done():
+				cancel()
+			case <-rctx.Done():
+			}
+		}()
+
+		rows, e := db.QueryContext(rctx, query, args...)
+		if e != nil {
+			o.sendQueryError(ctx, qctx, e, out)
+			return
+		}
+		defer rows.Close()
+		names, e := rows.Columns()
+		if e != nil {
+			o.sendQueryError(ctx, qctx, e, out)
+			return
+		}
+
+		var sf structFields
+		if proto != nil {
+			sf = *proto
+			sf.columns(names)
+		}
+		for rows.Next() {
+			var row interface{}
+			if proto != nil {
+				row, e = sf.scan(rows)
+			} else {
+				row, e = scanMap(rows, names)
+			}
+			if e != nil {
+				o.sendQueryError(ctx, qctx, e, out)
+				return
+			}
+			if b := o.sendToFlow(ctx, row, out); b {
+				return
+			}
+		}
+		if e = rows.Err(); e != nil {
+			o.sendQueryError(ctx, qctx, e, out)
+		}
+	}
+	o.operator = flipSource
+	return o
+}
+
+// send an error of the query unless unsubscribed, the error of qctx is sent if it is done
+func (o *Observable) sendQueryError(ctx, qctx context.Context, e error, out *flow) {
+	if ctx.Err() != nil {
+		return
+	}
+	if qe := qctx.Err(); qe != nil {
+		e = qe
+	}
+	o.sendToFlow(ctx, e, out)
+}
+
+// scan a row into a map by column names
+func scanMap(rows *sql.Rows, names []string) (interface{}, error) {
+	vals := make([]interface{}, len(names))
+	dest := make([]interface{}, len(names))
+	for i := range vals {
+		dest[i] = &vals[i]
+	}
+	if e := rows.Scan(dest...); e != nil {
+		return nil, e
+	}
+	m := make(map[string]interface{}, len(names))
+	for i, name := range names {
+		m[name] = vals[i]
+	}
+	return m, nil
+}
+
+// scan a row into fields of a new struct
+func (cs *structFields) scan(rows *sql.Rows) (interface{}, error) {
+	v := reflect.New(cs.t)
+	dest := make([]interface{}, len(cs.fields))
+	for col, i := range cs.fields {
+		if i < 0 {
+			dest[col] = new(interface{})
+		} else {
+			dest[col] = v.Elem().Field(i).Addr().Interface()
+		}
+	}
+	if e := rows.Scan(dest...); e != nil {
+		return nil, e
+	}
+	if cs.ptr {
+		return v.Interface(), nil
+	}
+	return v.Elem().Interface(), nil
+}
+
+// BatchInsert subscribes and executes query, an INSERT statement of a row, with each item as arguments.
+// An item is a []interface{} of arguments, or a struct or pointer to struct whose exported fields are arguments
+// in order, or a single argument. Items are inserted in a transaction per size items. It returns when completed
+// or on the first error flowed or returned by db, or on a nil pointer to struct, and the error is returned
+// after items received are inserted.
+// The transaction of a failed batch is rolled back.
+func (o *Observable) BatchInsert(ctx context.Context, db *sql.DB, query string, size int) error {
+	if size <= 0 {
+		panic(ErrFuncFlip)
+	}
+	structs := make(map[reflect.Type]*structFields)
+	batch := make([][]interface{}, 0, size)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		defer func() { batch = batch[:0] }()
+		tx, e := db.BeginTx(ctx, nil)
+		if e != nil {
+			return e
+		}
+		stmt, e := tx.PrepareContext(ctx, query)
+		if e == nil {
+			for _, args := range batch {
+				if _, e = stmt.ExecContext(ctx, args...); e != nil {
+					break
+				}
+			}
+			stmt.Close()
+		}
+		if e != nil {
+			tx.Rollback()
+			return e
+		}
+		return tx.Commit()
+	}
+
+	e := o.sink(ctx, func(x interface{}) error {
+		args, e := insertArgs(x, structs)
+		if e != nil {
+			return e
+		}
+		batch = append(batch, args)
+		if len(batch) < size {
+			return nil
+		}
+		return flush()
+	})
+	if fe := flush(); e == nil {
+		e = fe
+	}
+	return e
+}
+
+// arguments of an item of BatchInsert, or an error if it is a nil pointer to struct
+func insertArgs(x interface{}, structs map[reflect.Type]*structFields) ([]interface{}, error) {
+	if args, ok := x.([]interface{}); ok {
+		return args, nil
+	}
+	t := reflect.TypeOf(x)
+	if t == nil {
+		return []interface{}{nil}, nil
+	}
+	sf, ok := structs[t]
+	if !ok {
+		if sf = newStructFields(t, "db"); sf != nil {
+			sf.columns(nil)
+		}
+		structs[t] = sf
+	}
+	if sf == nil {
+		return []interface{}{x}, nil
+	}
+	v := reflect.ValueOf(x)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, fmt.Errorf("rxgo: nil %v can not be inserted", t)
+		}
+		v = v.Elem()
+	}
+	args := make([]interface{}, len(sf.fields))
+	for n, i := range sf.fields {
+		args[n] = v.Field(i).Interface()
+	}
+	return args, nil
+}
@@ -0,0 +1,218 @@
+package rxgo_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pmlpml/rxgo"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeDB is a database of the fake driver, a query selects Rows rows of (id, name, score),
+// and an insert statement records its arguments on commit and fails on an argument "bad"
+type fakeDB struct {
+	mu         sync.Mutex
+	Rows       int
+	inserted   [][]driver.Value
+	commits    int
+	rollbacks  int
+	rowsClosed int
+}
+
+var fakeDBs sync.Map
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	db, ok := fakeDBs.Load(name)
+	if !ok {
+		return nil, fmt.Errorf("no fake database %q", name)
+	}
+	return &fakeConn{db: db.(*fakeDB)}, nil
+}
+
+func init() {
+	sql.Register("rxgofake", fakeDriver{})
+}
+
+// open a fake database of rows
+func openFakeDB(t *testing.T, rows int) (*sql.DB, *fakeDB) {
+	fdb := &fakeDB{Rows: rows}
+	fakeDBs.Store(t.Name(), fdb)
+	db, _ := sql.Open("rxgofake", t.Name())
+	t.Cleanup(func() { db.Close() })
+	return db, fdb
+}
+
+type fakeConn struct {
+	db      *fakeDB
+	pending [][]driver.Value // inserted in the transaction
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) { return &fakeStmt{c}, nil }
+func (c *fakeConn) Close() error                              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)                 { return &fakeTx{c}, nil }
+
+type fakeTx struct {
+	c *fakeConn
+}
+
+func (tx *fakeTx) Commit() error {
+	tx.c.db.mu.Lock()
+	defer tx.c.db.mu.Unlock()
+	tx.c.db.commits++
+	tx.c.db.inserted = append(tx.c.db.inserted, tx.c.pending...)
+	tx.c.pending = nil
+	return nil
+}
+
+func (tx *fakeTx) Rollback() error {
+	tx.c.db.mu.Lock()
+	defer tx.c.db.mu.Unlock()
+	tx.c.db.rollbacks++
+	tx.c.pending = nil
+	return nil
+}
+
+type fakeStmt struct {
+	c *fakeConn
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	for _, a := range args {
+		if a == "bad" {
+			return nil, errors.New("bad argument")
+		}
+	}
+	s.c.pending = append(s.c.pending, args)
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return &fakeRows{db: s.c.db}, nil
+}
+
+type fakeRows struct {
+	db *fakeDB
+	n  int
+}
+
+func (r *fakeRows) Columns() []string { return []string{"id", "name", "score"} }
+
+func (r *fakeRows) Close() error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	r.db.rowsClosed++
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.n >= r.db.Rows {
+		return io.EOF
+	}
+	r.n++
+	dest[0], dest[1], dest[2] = int64(r.n), fmt.Sprintf("n%d", r.n), float64(r.n)/2
+	return nil
+}
+
+type queryRow struct {
+	ID    int64
+	Title string `db:"name"`
+}
+
+func TestFromQuery(t *testing.T) {
+	db, fdb := openFakeDB(t, 3)
+	res := []interface{}{}
+	rxgo.FromQuery(context.Background(), db, "SELECT id, name, score FROM t").Subscribe(collectItems(&res))
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"id": int64(1), "name": "n1", "score": 0.5},
+		map[string]interface{}{"id": int64(2), "name": "n2", "score": 1.0},
+		map[string]interface{}{"id": int64(3), "name": "n3", "score": 1.5},
+	}, res, "map rows error")
+
+	res = res[:0]
+	rxgo.FromQueryStruct(context.Background(), db, &queryRow{}, "SELECT id, name, score FROM t").Subscribe(collectItems(&res))
+	assert.Equal(t, []interface{}{&queryRow{1, "n1"}, &queryRow{2, "n2"}, &queryRow{3, "n3"}}, res, "struct rows error")
+
+	fdb.mu.Lock()
+	defer fdb.mu.Unlock()
+	assert.Equal(t, 2, fdb.rowsClosed, "rows are not closed")
+}
+
+func TestFromQueryCancel(t *testing.T) {
+	db, fdb := openFakeDB(t, 1<<30)
+
+	// unsubscription
+	n := 0
+	first := make(chan struct{})
+	sub := rxgo.FromQuery(context.Background(), db, "SELECT").SubscribeAsync(func(row map[string]interface{}) {
+		if n++; n == 10 {
+			close(first)
+		}
+	})
+	<-first
+	sub.Dispose()
+
+	// cancel of the query context is sent as error
+	ctx, cancel := context.WithCancel(context.Background())
+	var errs []error
+	rows := 0
+	rxgo.FromQuery(ctx, db, "SELECT").SubscribeFuncs(func(row map[string]interface{}) {
+		if rows++; rows == 10 {
+			cancel()
+		}
+	}, func(e error) { errs = append(errs, e) }, nil)
+	assert.Equal(t, []error{context.Canceled}, errs, "query context error")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		fdb.mu.Lock()
+		closed := fdb.rowsClosed
+		fdb.mu.Unlock()
+		if closed == 2 || time.Now().After(deadline) {
+			assert.Equal(t, 2, closed, "rows are not closed")
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+type insertRow struct {
+	ID   int64
+	Name string
+}
+
+func TestBatchInsert(t *testing.T) {
+	db, fdb := openFakeDB(t, 0)
+	err := rxgo.Just([]interface{}{int64(1), "a"}, insertRow{2, "b"}, &insertRow{3, "c"}, int64(4), "e").
+		BatchInsert(context.Background(), db, "INSERT INTO t VALUES (?, ?)", 2)
+	assert.NoError(t, err, "insert error")
+	fdb.mu.Lock()
+	assert.Equal(t, [][]driver.Value{{int64(1), "a"}, {int64(2), "b"}, {int64(3), "c"}, {int64(4)}, {"e"}}, fdb.inserted, "inserted rows error")
+	assert.Equal(t, 3, fdb.commits, "transactions error")
+	fdb.inserted = nil
+	fdb.mu.Unlock()
+
+	ee := errors.New("any")
+	err = rxgo.Just("a", "bad", "c", "d").BatchInsert(context.Background(), db, "INSERT", 2)
+	assert.Equal(t, "bad argument", err.Error(), "exec error")
+	err = rxgo.Just("x", ee, "y").BatchInsert(context.Background(), db, "INSERT", 2)
+	assert.Equal(t, ee, err, "flowed error")
+	err = rxgo.Just("z", (*insertRow)(nil)).BatchInsert(context.Background(), db, "INSERT", 2)
+	assert.Equal(t, "rxgo: nil *rxgo_test.insertRow can not be inserted", err.Error(), "nil struct error")
+
+	fdb.mu.Lock()
+	defer fdb.mu.Unlock()
+	assert.Equal(t, [][]driver.Value{{"x"}, {"z"}}, fdb.inserted, "items before errors are inserted")
+	assert.Equal(t, 1, fdb.rollbacks, "failed batch is not rolled back")
+}
@@ -0,0 +1,217 @@
+package rxgo_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pmlpml/rxgo"
+	"github.com/stretchr/testify/assert"
+)
+
+// stress every operator under all threading models, run with `go test -race`
+
+const stressItems = 1000
+
+var threadings = []rxgo.ThreadModel{rxgo.ThreadingDefault, rxgo.ThreadingIO, rxgo.ThreadingComputing}
+
+var stressOperators = []struct {
+	name   string
+	define func(source *rxgo.Observable) *rxgo.Observable
+	expect func(items []int) bool // check sorted items
+}{
+	{"Map", func(s *rxgo.Observable) *rxgo.Observable {
+		return s.Map(func(x int) int { return 2 * x })
+	}, func(items []int) bool {
+		return len(items) == stressItems && items[stressItems-1] == 2*(stressItems-1)
+	}},
+	{"Filter", func(s *rxgo.Observable) *rxgo.Observable {
+		return s.Filter(func(x int) bool { return x%2 == 0 })
+	}, func(items []int) bool {
+		return len(items) == stressItems/2
+	}},
+	{"FlatMap", func(s *rxgo.Observable) *rxgo.Observable {
+		return s.FlatMap(func(x int) *rxgo.Observable { return rxgo.Just(x, x) })
+	}, func(items []int) bool {
+		return len(items) == 2*stressItems
+	}},
+	{"TransformOp", func(s *rxgo.Observable) *rxgo.Observable {
+		return s.TransformOp(func(ctx context.Context, item interface{}, send func(x interface{}) (endSignal bool)) {
+			send(item)
+		})
+	}, func(items []int) bool {
+		return len(items) == stressItems
+	}},
+	{"First", func(s *rxgo.Observable) *rxgo.Observable {
+		return s.First()
+	}, func(items []int) bool {
+		return len(items) == 1 && items[0] == 0
+	}},
+	{"Last", func(s *rxgo.Observable) *rxgo.Observable {
+		return s.Last()
+	}, func(items []int) bool {
+		return len(items) == 1 && items[0] == stressItems-1
+	}},
+	{"Distinct", func(s *rxgo.Observable) *rxgo.Observable {
+		return s.Map(func(x int) int { return x % 10 }).SubscribeOn(rxgo.ThreadingDefault).Distinct()
+	}, func(items []int) bool {
+		return len(items) == 10
+	}},
+	{"Skip", func(s *rxgo.Observable) *rxgo.Observable {
+		return s.Skip(10)
+	}, func(items []int) bool {
+		return len(items) == stressItems-10
+	}},
+	{"SkipLast", func(s *rxgo.Observable) *rxgo.Observable {
+		return s.SkipLast(10)
+	}, func(items []int) bool {
+		return len(items) == stressItems-10
+	}},
+	{"Take", func(s *rxgo.Observable) *rxgo.Observable {
+		return s.Take(10)
+	}, func(items []int) bool {
+		return len(items) == 10
+	}},
+	{"TakeLast", func(s *rxgo.Observable) *rxgo.Observable {
+		return s.TakeLast(10)
+	}, func(items []int) bool {
+		return len(items) == 10 && items[0] == stressItems-10
+	}},
+	{"ElementAt", func(s *rxgo.Observable) *rxgo.Observable {
+		return s.ElementAt(5)
+	}, func(items []int) bool {
+		return len(items) == 1 && items[0] == 4
+	}},
+	{"Debounce", func(s *rxgo.Observable) *rxgo.Observable {
+		return pauseLast(s).Debounce(time.Nanosecond)
+	}, func(items []int) bool {
+		return increasing(items) && items[len(items)-1] == stressItems-1
+	}},
+	{"Sample", func(s *rxgo.Observable) *rxgo.Observable {
+		return pauseLast(s).Sample(time.Microsecond)
+	}, func(items []int) bool {
+		return increasing(items) && items[len(items)-1] == stressItems-1
+	}},
+}
+
+// operators emitting items in the order received on ThreadingDefault
+var orderedOperators = map[string]bool{"Debounce": true, "Sample": true}
+
+// pause before the last item, so it is not dropped by time
+func pauseLast(s *rxgo.Observable) *rxgo.Observable {
+	return s.Map(func(x int) int {
+		if x == stressItems-1 {
+			time.Sleep(time.Millisecond)
+		}
+		return x
+	})
+}
+
+// items are not empty and strictly increasing
+func increasing(items []int) bool {
+	for i := 1; i < len(items); i++ {
+		if items[i] <= items[i-1] {
+			return false
+		}
+	}
+	return len(items) > 0
+}
+
+func TestOperatorsStress(t *testing.T) {
+	for _, op := range stressOperators {
+		for _, th := range threadings {
+			op, th := op, th
+			t.Run(fmt.Sprintf("%s/%s", op.name, th), func(t *testing.T) {
+				ob := op.define(rxgo.Range(0, stressItems)).SubscribeOn(th)
+
+				// subscribe the same definition concurrently
+				var wg sync.WaitGroup
+				for i := 0; i < 4; i++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						var mu sync.Mutex
+						items := []int{}
+						ob.Subscribe(rxgo.ObserverMonitor{
+							Next: func(x interface{}) {
+								mu.Lock()
+								items = append(items, x.(int))
+								mu.Unlock()
+							},
+						})
+						if orderedOperators[op.name] && th == rxgo.ThreadingDefault {
+							assert.True(t, increasing(items), "%s on %s is out of order", op.name, th)
+						}
+						sort.Ints(items)
+						assert.True(t, op.expect(items), "%s on %s got %d items", op.name, th, len(items))
+					}()
+				}
+				wg.Wait()
+			})
+		}
+	}
+}
+
+func TestChainStress(t *testing.T) {
+	for _, th := range threadings {
+		sum := 0
+		rxgo.Range(0, stressItems).Map(func(x int) int {
+			return x + 1
+		}).SubscribeOn(th).Filter(func(x int) bool {
+			return x%2 == 0
+		}).SubscribeOn(th).FlatMap(func(x int) *rxgo.Observable {
+			return rxgo.Just(x, -x)
+		}).SubscribeOn(th).Map(func(x int) int {
+			if x < 0 {
+				return 0
+			}
+			return x
+		}).SubscribeOn(th).Subscribe(func(x int) {
+			sum += x
+		})
+		assert.Equal(t, 250500, sum, "chain on %s error", th)
+	}
+}
+
+func TestUnhashableItemsStress(t *testing.T) {
+	filters := map[string]func(s *rxgo.Observable) *rxgo.Observable{
+		"First":     func(s *rxgo.Observable) *rxgo.Observable { return s.First() },
+		"Last":      func(s *rxgo.Observable) *rxgo.Observable { return s.Last() },
+		"Take":      func(s *rxgo.Observable) *rxgo.Observable { return s.Take(3) },
+		"Skip":      func(s *rxgo.Observable) *rxgo.Observable { return s.Skip(3) },
+		"ElementAt": func(s *rxgo.Observable) *rxgo.Observable { return s.ElementAt(2) },
+		"Debounce":  func(s *rxgo.Observable) *rxgo.Observable { return s.Debounce(time.Nanosecond) },
+		"Sample":    func(s *rxgo.Observable) *rxgo.Observable { return s.Sample(time.Microsecond) },
+	}
+	for name, define := range filters {
+		for _, th := range threadings {
+			items, errs := 0, 0
+			define(rxgo.Range(0, 10).Map(func(x int) []byte {
+				return []byte{byte(x)}
+			})).SubscribeOn(th).Subscribe(rxgo.ObserverMonitor{
+				Next:  func(x interface{}) { items++ },
+				Error: func(e error) { errs++ },
+			})
+			assert.True(t, items > 0, "%s on %s got no items", name, th)
+			assert.Equal(t, 0, errs, "%s on %s got errors", name, th)
+		}
+	}
+
+	// Distinct can not compare them
+	for _, th := range threadings {
+		var te *rxgo.ItemTypeError
+		n := 0
+		rxgo.Just([]byte("a"), "b", "b").Distinct().SubscribeOn(th).Subscribe(rxgo.ObserverMonitor{
+			Next: func(x interface{}) { n++ },
+			Error: func(e error) {
+				assert.True(t, errors.As(e, &te), "not an ItemTypeError")
+			},
+		})
+		assert.True(t, te != nil, "Distinct on %s accepts unhashable items", th)
+		assert.Equal(t, 1, n, "Distinct on %s items error", th)
+	}
+}
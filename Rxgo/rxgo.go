@@ -8,8 +8,11 @@ package rxgo
 import (
 	"context"
 	"errors"
+	"fmt"
 	"reflect"
+	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -21,8 +24,41 @@ const (
 	ThreadingComputing                    // each item served by one goroutine in a limited group
 )
 
+func (t ThreadModel) String() string {
+	switch t {
+	case ThreadingDefault:
+		return "default"
+	case ThreadingIO:
+		return "io"
+	case ThreadingComputing:
+		return "computing"
+	}
+	return "unknown"
+}
+
+// Coercion is the policy to pass an item to parameter of user function
+type Coercion uint
+
+const (
+	CoercionAssignable  Coercion = iota // item must be assignable to the parameter
+	CoercionStrict                      // item must be the type of the parameter or implement the interface of it
+	CoercionConvertible                 // item is assignable, or a number converted to the numeric parameter without loss
+)
+
+func (c Coercion) String() string {
+	switch c {
+	case CoercionAssignable:
+		return "assignable"
+	case CoercionStrict:
+		return "strict"
+	case CoercionConvertible:
+		return "convertible"
+	}
+	return "unknown"
+}
+
 // Subscribe paeameter error
-var ErrFuncOnNext = errors.New("Subscribe paramteter needs func(x anytype) or Observer or ObserverWithContext")
+var ErrFuncOnNext = errors.New("Subscribe paramteter needs func(x anytype), func(x anytype, err error), Observer or ObserverWithContext")
 
 // operator func error
 var ErrFuncFlip = errors.New("Operator Func Error")
@@ -43,6 +79,38 @@ func (e FlowableError) Error() string {
 	return e.Err.Error()
 }
 
+// OperatorError is a panic of user function recovered in a stage. It flows to subscriber as an error.
+type OperatorError struct {
+	Stage string      // name of the stage
+	Item  interface{} // item passed to the user function, nil for a source
+	Value interface{} // value of the panic
+	Stack []byte      // stack trace of the panic
+}
+
+func (e *OperatorError) Error() string {
+	return fmt.Sprintf("panic in %s with item %v: %v", e.Stage, e.Item, e.Value)
+}
+
+// Unwrap returns the panic value if it is an error
+func (e *OperatorError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// ItemTypeError is an item that can not be passed to user function of a stage by its Coercion. It flows to subscriber as an error.
+type ItemTypeError struct {
+	Stage string       // name of the stage
+	Item  interface{}  // item of the flow
+	Type  reflect.Type // type of the parameter of user function, nil if the item should be comparable
+}
+
+func (e *ItemTypeError) Error() string {
+	if e.Type == nil {
+		return fmt.Sprintf("%s can not compare item of type %T", e.Stage, e.Item)
+	}
+	return fmt.Sprintf("%s can not pass item of type %T to parameter of type %v", e.Stage, e.Item, e.Type)
+}
+
 // Observer subscribes to an Observable. Then that observer reacts to whatever item or sequence of items the Observable emits.
 type Observer interface {
 	OnNext(x interface{})
@@ -106,7 +174,86 @@ func (o ObserverMonitor) Unsubscribe() {
 }
 
 type streamOperator interface {
-	op(ctx context.Context, o *Observable)
+	op(ctx context.Context, o *Observable, in, out *flow)
+}
+
+// flow of items from a stage to the next one. flows are created for each subscription.
+// A fused flow has no ch, items are passed to the receiver by the goroutine of the sender.
+type flow struct {
+	ch        chan interface{}
+	processed atomic.Uint64      // items sent to ch
+	cancel    context.CancelFunc // stop the stage sending to ch and all stages before it
+	wg        *sync.WaitGroup    // goroutines of all stages in the subscription
+	fused     bool               // the next stage is fused into the sender
+	recv      *receiver          // receiver of the fused flow
+	batch     *batcher           // send items to ch in batches, nil if batching is disabled
+	options   *Options           // options of the chain, nil if not configured
+	debug     Observer           // monitor of the stage sending to ch, nil if not set
+	bare      bool               // no metrics, tracing or monitor is on the flow
+}
+
+// subscriptionMonitor is a monitor creating an observer with its own state for each subscription
+type subscriptionMonitor interface {
+	subscribe(stage string) Observer
+}
+
+// receiver processes items of a flow in the next stage
+type receiver struct {
+	next     func(x interface{}) (stop bool) // process an item, returns true if the stage does not receive items any more
+	complete func()                          // called after the last item
+}
+
+// receive items of flow in by r. r is called by the goroutine of the sender if the flow is fused,
+// otherwise by a new goroutine of stage o
+func (o *Observable) receive(ctx context.Context, in, out *flow, r *receiver) {
+	if in.fused {
+		in.recv = r
+		return
+	}
+
+	o.goroutineStarted(ctx)
+	out.wg.Add(1)
+	go func() {
+		defer out.wg.Done()
+		defer o.goroutineStopped(ctx)
+		if in.forEach(ctx, r.next) {
+			// stop stages before and drain items left
+			in.cancel()
+			for range in.ch {
+			}
+		}
+		r.complete()
+	}()
+}
+
+// receive items from the channel of the flow in order until it is closed or f returns true.
+// It returns true if stopped by f, or by ctx of the receiver in the middle of a batch.
+func (fl *flow) forEach(ctx context.Context, f func(x interface{}) (stop bool)) bool {
+	for x := range fl.ch {
+		if items, ok := x.(itemBatch); ok {
+			for i, x := range items {
+				// items after the canceled one would not be sent without batching
+				if i > 0 && ctx.Err() != nil {
+					return true
+				}
+				if f(x) {
+					return true
+				}
+			}
+		} else if f(x) {
+			return true
+		}
+	}
+	return false
+}
+
+// stop the stage sending to this flow and all stages before it, then wait until their goroutines exit.
+// It must be called by the receiver of the flow when it does not receive items any more.
+func (f *flow) dispose() {
+	f.cancel()
+	for range f.ch {
+	}
+	f.wg.Wait()
 }
 
 //emit something
@@ -121,23 +268,33 @@ var BufferLen uint = 128
 // An Observable is a 'collection of items that arrive over time'. Observables can be used to model asynchronous events.
 // Observables can also be chained by operators to transformed, combined those items
 // The Observable's operators, by default, run with a channel size of 128 elements except that the source (first) observable has no buffer
+// A chain of Observables is a definition. Each subscription creates its own channels and goroutines,
+// so an Observable can be subscribed many times concurrently or be chained by many operators.
 type Observable struct {
 	Name string
-	mu   sync.Mutex // lock all when creating subscriber
+	kind string     // operator kind, such as "map" or "Just"
+	mu   sync.Mutex // guard runtime status and options
 	//
-	flip     interface{} // transformation function
-	outflow  chan interface{}
-	operator streamOperator
-	// chain of Observables
-	root *Observable
-	next *Observable
-	pred *Observable
+	flip      interface{} // transformation function
+	flip_fast fastFunc    // call flip function without reflection, nil if not supported
+	operator  streamOperator
+	// chain of Observables. a chain is a definition and never changed by subscriptions
+	root      *Observable
+	pred      *Observable
+	inner     []*Observable              // inner Observables known when defined, such as From(*Observable)
+	lastInner atomic.Pointer[Observable] // inner Observable created at runtime lastly, such as FlatMap
 	// control model
-	threading ThreadModel //threading model. if this is root, it represents obseverOn model
-	buf_len   uint
+	threading     ThreadModel //threading model. if this is root, it represents obseverOn model
+	threading_set bool        // threading is set by SubscribeOn, not by chain options
+	buf_len       uint
+	buf_set       bool     // buf_len is set by SetBufferLen, not by chain options
+	options       *Options // options configured on this stage, inherited by stages after it
+	// runtime status
+	lastFlows []*flow // flows of the latest subscription, guarded by mu
 	// utility vars
 	debug             Observer
 	flip_sup_ctx      bool //indicate that flip function use context as first paramter
+	flip_ret_error    bool // indicate that flip function returns an error as the last result
 	flip_accept_error bool // indicate that flip function input's data is type interface{} or error
 	debounce   time.Duration // 过滤发射速率过快的数据项
 	distinct   bool          // 抑制（过滤掉）重复的数据项
@@ -154,17 +311,126 @@ func newObservable() *Observable {
 	return &Observable{}
 }
 
-// connect all Observable form the first one.
-func (o *Observable) connect(ctx context.Context) {
-	for po := o.root; po != nil; po = po.next {
-		po.outflow = make(chan interface{}, po.buf_len)
-		po.operator.op(ctx, po)
-		//fmt.Println("conneted", po.name, po.outflow)
+// scheduler runs operations of a stage in a subscription by its threading model
+type scheduler struct {
+	ctx       context.Context // context of the stage
+	o         *Observable
+	threading ThreadModel
+	wg        sync.WaitGroup
+	group     chan struct{} // limited group of ThreadingComputing
+	end       atomic.Bool   // an operation signals end of the flow
+}
+
+func newScheduler(ctx context.Context, o *Observable) *scheduler {
+	s := &scheduler{ctx: ctx, o: o, threading: o.stageThreading(optionsOf(ctx))}
+	if s.threading == ThreadingComputing {
+		s.group = make(chan struct{}, runtime.NumCPU())
+	}
+	return s
+}
+
+// run the operation which returns true when the flow should end
+func (s *scheduler) run(f func() (end bool)) {
+	switch s.threading {
+	case ThreadingDefault:
+		if f() {
+			s.end.Store(true)
+		}
+	case ThreadingIO, ThreadingComputing:
+		if s.group != nil {
+			s.group <- struct{}{}
+		}
+		s.wg.Add(1)
+		s.o.goroutineStarted(s.ctx)
+		go func() {
+			defer func() {
+				s.o.goroutineStopped(s.ctx)
+				if s.group != nil {
+					<-s.group
+				}
+				s.wg.Done()
+			}()
+			if f() {
+				s.end.Store(true)
+			}
+		}()
+	default:
+	}
+}
+
+// is end of the flow signaled by any operation
+func (s *scheduler) ended() bool {
+	return s.end.Load()
+}
+
+// waiting all operations completed
+func (s *scheduler) wait() {
+	s.wg.Wait()
+}
+
+// get all Observables from the first one to o
+func (o *Observable) chain() (stages []*Observable) {
+	for po := o; po != nil; po = po.pred {
+		stages = append(stages, po)
+	}
+	for i, j := 0, len(stages)-1; i < j; i, j = i+1, j-1 {
+		stages[i], stages[j] = stages[j], stages[i]
+	}
+	return
+}
+
+// connect all Observable form the first one to o with new flows, and returns the flow of o.
+// The receiver of the returned flow must dispose it.
+func (o *Observable) connect(ctx context.Context) *flow {
+	stages := o.chain()
+	flows := make([]*flow, len(stages))
+	// all stages run with options of this chain, though they may be shared by other chains
+	op := o.chainOptions()
+	ctx = context.WithValue(ctx, chainOptionsKey{}, op)
+
+	// context of a stage is a child of the next one, so a stage can stop all stages before it
+	ctxs := make([]context.Context, len(stages))
+	cancels := make([]context.CancelFunc, len(stages))
+	for i := len(stages) - 1; i >= 0; i-- {
+		ctx, cancels[i] = context.WithCancel(ctx)
+		ctxs[i] = ctx
+	}
+
+	wg := new(sync.WaitGroup)
+	for i, po := range stages {
+		flows[i] = &flow{cancel: cancels[i], wg: wg, options: op, debug: po.debug}
+		if m, ok := po.debug.(subscriptionMonitor); ok {
+			flows[i].debug = m.subscribe(po.stageName(op))
+		}
+		flows[i].bare = flows[i].debug == nil && (op == nil || op.Metrics == nil && op.Tracing == nil)
+		// a stage with a buffer set by SetBufferLen or by options other than BufferLen keeps the channel to the next one
+		buffered := po.buf_set || po.stageBufferLen(op) != BufferLen
+		if i+1 < len(stages) && po.fusable(op) && !buffered && stages[i+1].fusable(op) {
+			flows[i].fused = true
+		} else {
+			flows[i].ch = make(chan interface{}, po.stageBufferLen(op))
+			flows[i].batch = po.newBatcher(ctxs[i], flows[i].ch)
+		}
+	}
+	// connect from the last stage, so receivers of fused flows are set before their senders start
+	for i := len(stages) - 1; i >= 0; i-- {
+		var in *flow
+		if i > 0 {
+			in = flows[i-1]
+		}
+		stages[i].operator.op(ctxs[i], stages[i], in, flows[i])
+		//fmt.Println("conneted", po.name, out)
 	}
+
+	o.mu.Lock()
+	o.lastFlows = flows
+	o.mu.Unlock()
+	return flows[len(flows)-1]
 }
 
 func (o *Observable) SubscribeOn(t ThreadModel) *Observable {
 	o.threading = t
+	o.threading_set = true
 	return o
 }
 
@@ -174,76 +440,208 @@ func (o *Observable) ObserveOn(t ThreadModel) *Observable {
 	return o
 }
 
+// Subscribe connects the chain and observes it until completed.
+// The subscriber is an Observer, or a function `func(x anytype)` which skips errors, or `func(x anytype, err error)`
+// which gets an error with zero x. An item which can not be passed to x by the coercion policy is sent as
+// ItemTypeError to `func(x anytype, err error)`, and panics with it for `func(x anytype)`.
+// It returns after all goroutines of the subscription exit.
 func (o *Observable) Subscribe(ob interface{}) {
-	o.mu.Lock()
-	fv, ft := reflect.ValueOf(ob), reflect.TypeOf(ob)
+	observer := o.observerOf(ob)
+	o.observe(observerContext(observer), observer)
+}
+
+// SubscribeFuncs subscribes with onNext `func(x anytype)`, onError and onCompleted. Any of them can be nil.
+// An item which can not be passed to onNext by the coercion policy is sent to onError as ItemTypeError,
+// and panics with it if onError is nil.
+func (o *Observable) SubscribeFuncs(onNext interface{}, onError func(error), onCompleted func()) {
+	f := &funcObserver{coercion: o.stageCoercion(o.chainOptions()), onError: onError, onCompleted: onCompleted}
+	if onNext != nil {
+		f.next = reflect.ValueOf(onNext)
+		if f.next.Kind() != reflect.Func || f.next.Type().NumIn() != 1 || f.next.Type().NumOut() != 0 {
+			panic(fmt.Errorf("%w, got %T", ErrFuncOnNext, onNext))
+		}
+	}
+	o.observe(context.Background(), f)
+}
+
+// Subscription is a subscription running in background
+type Subscription struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// SubscribeAsync subscribes in a new goroutine and returns the Subscription
+func (o *Observable) SubscribeAsync(ob interface{}) *Subscription {
+	observer := o.observerOf(ob)
+	ctx, cancel := context.WithCancel(observerContext(observer))
+	s := &Subscription{cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(s.done)
+		defer cancel()
+		o.observe(ctx, observer)
+	}()
+	return s
+}
+
+// Dispose unsubscribes and waits until all goroutines of the subscription exit
+func (s *Subscription) Dispose() {
+	s.cancel()
+	<-s.done
+}
+
+// Wait waits until the subscription is completed and all goroutines of it exit
+func (s *Subscription) Wait() {
+	<-s.done
+}
+
+// Done is closed when the subscription is completed and all goroutines of it exit
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// get observer of subscriber, a function is wrapped as funcObserver
+func (o *Observable) observerOf(ob interface{}) Observer {
+	if observer, ok := ob.(Observer); ok {
+		return observer
+	}
+
+	// observe function `func(x anytype)` or `func(x anytype, err error)`
+	fv := reflect.ValueOf(ob)
+	if fv.Kind() != reflect.Func {
+		panic(fmt.Errorf("%w, got %T", ErrFuncOnNext, ob))
+	}
+	ft := fv.Type()
+	f := &funcObserver{coercion: o.stageCoercion(o.chainOptions()), next: fv}
+	switch {
+	case ft.NumOut() != 0:
+		panic(fmt.Errorf("%w, got %v", ErrFuncOnNext, ft))
+	case ft.NumIn() == 1:
+	case ft.NumIn() == 2 && ft.In(1) == typeError:
+		f.withError = true
+	default:
+		panic(fmt.Errorf("%w, got %v", ErrFuncOnNext, ft))
+	}
+	return f
+}
 
-	var observer Observer
+// funcObserver is an Observer calling functions of subscriber.
+// Items are passed to next by the coercion policy of the chain, which is resolved when subscribed.
+type funcObserver struct {
+	coercion    Coercion
+	next        reflect.Value // `func(x anytype)` or `func(x anytype, err error)`, invalid if not set
+	withError   bool          // next accepts errors
+	onError     func(error)
+	onCompleted func()
+}
 
-	// observe function `func(x anytype)`
-	if fv.Kind() == reflect.Func {
-		if ft.NumIn() == 1 && ft.NumOut() != 0 {
-			panic(ErrFuncOnNext)
+func (f *funcObserver) OnNext(x interface{}) {
+	if !f.next.IsValid() {
+		return
+	}
+	t := f.next.Type().In(0)
+	xv, ok := coerceValue(reflect.ValueOf(x), t, f.coercion)
+	if !ok {
+		e := &ItemTypeError{Stage: "subscribe", Item: x, Type: t}
+		if !f.withError && f.onError == nil {
+			// nobody gets the error, fail loudly instead of losing the item
+			panic(e)
 		}
+		f.OnError(e)
+		return
+	}
+	if f.withError {
+		f.next.Call([]reflect.Value{xv, reflect.Zero(typeError)})
 	} else {
-		st := reflect.TypeOf((*Observer)(nil)).Elem() // get type of *Observer
-		//fmt.Println("ffffffffffffff", ft, st, ft.Implements(st))
-		if ft.Implements(st) {
-			observer = ob.(Observer)
-		} else {
-			panic(ErrFuncOnNext)
-		}
+		f.next.Call([]reflect.Value{xv})
+	}
+}
+
+func (f *funcObserver) OnError(e error) {
+	if f.withError {
+		f.next.Call([]reflect.Value{reflect.Zero(f.next.Type().In(0)), reflect.ValueOf(&e).Elem()})
+	}
+	if f.onError != nil {
+		f.onError(e)
 	}
+}
 
-	oc, ctxok := observer.(ObserverWithContext)
-	ctx := context.Background()
+func (f *funcObserver) OnCompleted() {
+	if f.onCompleted != nil {
+		f.onCompleted()
+	}
+}
 
-	if ctxok {
-		ctx = oc.GetObserverContext()
+// get context of observer
+func observerContext(observer Observer) context.Context {
+	if oc, ok := observer.(ObserverWithContext); ok {
 		//fmt.Println("ctx geted!", ctx)
+		return oc.GetObserverContext()
 	}
+	return context.Background()
+}
 
+func (o *Observable) observe(ctx context.Context, observer Observer) {
 	//fmt.Println("begin conneted", o.name)
-	o.connect(ctx)
-	if ctxok {
+	in := o.connect(ctx)
+	if oc, ok := observer.(ObserverWithContext); ok {
 		oc.OnConnected()
 	}
 
-	//get the last ob servable
-	po := o
-	for ; po.next != nil; po = po.next {
-	}
-
-	in := po.outflow
-	o.mu.Unlock()
-
-	for x := range in {
-		if observer != nil {
-			if e, ok := x.(error); ok {
-				observer.OnError(e)
-
-			} else {
-				observer.OnNext(x)
-			}
+	defer func() {
+		// stop the subscription before a panic of the observer leaves
+		if e := recover(); e != nil {
+			in.dispose()
+			panic(e)
+		}
+	}()
+	in.forEach(ctx, func(x interface{}) bool {
+		_, x = recvItem(ctx, x)
+		if e, ok := x.(error); ok {
+			observer.OnError(e)
 		} else {
-			if _, ok := x.(error); ok {
-				// skip error
-			} else {
-				params := []reflect.Value{reflect.ValueOf(x)}
-				fv.Call(params)
-			}
+			observer.OnNext(x)
 		}
-	}
-	if observer != nil {
-		observer.OnCompleted()
-	}
+		return false
+	})
+	in.dispose()
+	observer.OnCompleted()
 }
 
 func (o *Observable) SetBufferLen(length uint) *Observable {
 	o.buf_len = length
+	o.buf_set = true
 	return o
 }
 
+// StageStats is a snapshot of a stage of running Observables
+type StageStats struct {
+	Name      string
+	Len       int    // items waiting in the output channel
+	Cap       int    // buffer length of the output channel
+	Processed uint64 // items sent to the output channel, 0 if the stage is fused with the next one
+}
+
+// Stats reports each stage from the source to this Observable in the latest subscription to it
+func (o *Observable) Stats() []StageStats {
+	stages := o.chain()
+	o.mu.Lock()
+	flows := o.lastFlows
+	o.mu.Unlock()
+
+	op := o.chainOptions()
+	stats := make([]StageStats, 0, len(stages))
+	for i, po := range stages {
+		st := StageStats{Name: po.stageName(op)}
+		if i < len(flows) {
+			st.Len = len(flows[i].ch)
+			st.Cap = cap(flows[i].ch)
+			st.Processed = flows[i].processed.Load()
+		}
+		stats = append(stats, st)
+	}
+	return stats
+}
+
 // set a observer to monite items in data stream
 func (o *Observable) SetMonitor(observer Observer) *Observable {
 	o.debug = observer
@@ -261,31 +659,82 @@ func (o *Observable) Debug(debug bool) *Observable {
 	return o
 }
 
-func (o *Observable) sendToFlow(ctx context.Context, item interface{}, out chan interface{}) (end bool) {
+func (o *Observable) sendToFlow(ctx context.Context, item interface{}, out *flow) (end bool) {
 	//fmt.Println("send chan ", o.name, item, out)
-	select {
-	case out <- item:
-		if e, ok := item.(error); ok {
-			if o.debug != nil {
-				o.debug.OnError(e)
-			}
-		} else {
-			if o.debug != nil {
-				o.debug.OnNext(item)
-			}
+	if out.fused && out.bare {
+		// pass the item to the next stage directly, only stages with a channel count processed items
+		select {
+		case <-ctx.Done():
+			return true
+		default:
+		}
+		if out.recv.next(item) {
+			out.cancel()
+			return true
+		}
+		return false
+	}
+	m := out.metrics()
+	var start time.Time
+	if m != nil {
+		start = time.Now()
+	}
+	x := o.traceItem(ctx, out.options, item)
+	switch {
+	case out.fused:
+		if ctx.Err() != nil {
+			return true
+		}
+	case out.batch != nil:
+		if out.batch.add(ctx, x) {
+			return true
+		}
+	default:
+		select {
+		case out.ch <- x:
+		case <-ctx.Done():
+			return true
 		}
-	case <-ctx.Done():
+	}
+
+	out.processed.Add(1)
+	if e, ok := item.(error); ok {
+		if m != nil {
+			m.ErrorSent(o.stageName(out.options))
+		}
+		if out.debug != nil {
+			out.debug.OnError(e)
+		}
+	} else {
+		if m != nil {
+			m.ItemSent(o.stageName(out.options), time.Since(start))
+		}
+		if out.debug != nil {
+			out.debug.OnNext(item)
+		}
+	}
+	if out.fused && out.recv.next(x) {
+		// the next stage stops receiving, stop this stage and all stages before it
+		out.cancel()
 		end = true
 	}
 	return
 }
 
-func (o *Observable) closeFlow(out chan interface{}) *Observable {
+func (o *Observable) closeFlow(out *flow) *Observable {
 	// maybe need waiting for parent observable closed
 	//fmt.Println("close chan ", o.name, out)
-	close(out)
-	if o.debug != nil {
-		o.debug.OnCompleted()
+	// notify monitors before closing, so they are done when the subscriber completes
+	if out.debug != nil {
+		out.debug.OnCompleted()
+	}
+	switch {
+	case out.fused:
+		out.recv.complete()
+	case out.batch != nil:
+		out.batch.close()
+	default:
+		close(out.ch)
 	}
 	return o
 }
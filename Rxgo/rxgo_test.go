@@ -1,11 +1,17 @@
 package rxgo_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"os/exec"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/pmlpml/rxgo"
+	"github.com/stretchr/testify/assert"
 )
 
 type observer struct {
@@ -62,3 +68,199 @@ func TestTreading(t *testing.T) {
 	flow.Subscribe(observer{"test flatMap again"})
 	time.Sleep(time.Microsecond * 1000)
 }
+
+func TestBranching(t *testing.T) {
+	source := rxgo.Just(1, 2, 3)
+	double := source.Map(func(x int) int {
+		return 2 * x
+	})
+	odd := source.Filter(func(x int) bool {
+		return x%2 == 1
+	})
+
+	res1, res2, res3 := []int{}, []int{}, []int{}
+	double.Subscribe(func(x int) {
+		res1 = append(res1, x)
+	})
+	odd.Subscribe(func(x int) {
+		res2 = append(res2, x)
+	})
+	source.Subscribe(func(x int) {
+		res3 = append(res3, x)
+	})
+	assert.Equal(t, []int{2, 4, 6}, res1, "Map branch error")
+	assert.Equal(t, []int{1, 3}, res2, "Filter branch error")
+	assert.Equal(t, []int{1, 2, 3}, res3, "source error")
+}
+
+func TestConcurrentSubscribe(t *testing.T) {
+	flow := rxgo.Range(0, 100).Map(func(x int) int {
+		return x + 1
+	}).Filter(func(x int) bool {
+		return x%2 == 0
+	})
+
+	var wg sync.WaitGroup
+	sums := make([]int, 8)
+	for i := range sums {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			flow.Subscribe(func(x int) {
+				sums[i] += x
+			})
+		}(i)
+	}
+	wg.Wait()
+	for _, sum := range sums {
+		assert.Equal(t, 2550, sum, "concurrent subscription error")
+	}
+}
+
+func TestOperatorError(t *testing.T) {
+	var errs []error
+	res := []int{}
+	rxgo.Just(1, 2, 3).Map(func(x int) int {
+		if x == 2 {
+			panic("bad item")
+		}
+		return x
+	}).Subscribe(rxgo.ObserverMonitor{
+		Next: func(x interface{}) {
+			res = append(res, x.(int))
+		},
+		Error: func(e error) {
+			errs = append(errs, e)
+		},
+	})
+
+	assert.Equal(t, []int{1, 3}, res, "items error")
+	assert.Equal(t, 1, len(errs), "errors count error")
+	var oe *rxgo.OperatorError
+	assert.True(t, errors.As(errs[0], &oe), "not an OperatorError")
+	assert.Equal(t, "map", oe.Stage, "stage error")
+	assert.Equal(t, 2, oe.Item, "item error")
+	assert.Equal(t, "bad item", oe.Value, "panic value error")
+	assert.Contains(t, string(oe.Stack), "TestOperatorError", "stack error")
+}
+
+func TestOperatorErrorOfSource(t *testing.T) {
+	ee := errors.New("broken")
+	var errs []error
+	rxgo.Generator(func(ctx context.Context, send func(x interface{}) (endSignal bool)) {
+		send(1)
+		panic(ee)
+	}).Subscribe(rxgo.ObserverMonitor{
+		Error: func(e error) {
+			errs = append(errs, e)
+		},
+	})
+	assert.Equal(t, 1, len(errs), "errors count error")
+	assert.True(t, errors.Is(errs[0], ee), "unwrap error")
+}
+
+func TestCrashOnPanic(t *testing.T) {
+	if os.Getenv("RXGO_CRASH_ON_PANIC") == "1" {
+		rxgo.Just(1).Map(func(x int) int {
+			panic("crash now")
+		}).Configure(rxgo.WithCrashOnPanic(true)).Subscribe(func(x int) {})
+		return
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestCrashOnPanic$")
+	cmd.Env = append(os.Environ(), "RXGO_CRASH_ON_PANIC=1")
+	out, err := cmd.CombinedOutput()
+	assert.Error(t, err, "process should crash")
+	assert.Contains(t, string(out), "panic: crash now", "panic value error")
+}
+
+func TestSubscribeFuncs(t *testing.T) {
+	res := []int{}
+	var errs []error
+	completed := false
+	rxgo.Just(1, "two", errors.New("any"), 3).SubscribeFuncs(func(x int) {
+		res = append(res, x)
+	}, func(e error) {
+		errs = append(errs, e)
+	}, func() {
+		completed = true
+	})
+
+	assert.Equal(t, []int{1, 3}, res, "items error")
+	assert.Equal(t, 2, len(errs), "errors count error")
+	var te *rxgo.ItemTypeError
+	assert.True(t, errors.As(errs[0], &te), "not an ItemTypeError")
+	assert.Equal(t, "two", te.Item, "item error")
+	assert.True(t, completed, "not completed")
+
+	count := 0
+	rxgo.Just(1, 2).SubscribeFuncs(nil, nil, func() {
+		count++
+	})
+	assert.Equal(t, 1, count, "nil callbacks error")
+}
+
+func TestSubscribeFuncWithError(t *testing.T) {
+	res := []int{}
+	errs := 0
+	rxgo.Just(1, errors.New("any"), 3).Subscribe(func(x int, err error) {
+		if err != nil {
+			assert.Equal(t, 0, x, "zero item error")
+			errs++
+			return
+		}
+		res = append(res, x)
+	})
+	assert.Equal(t, []int{1, 3}, res, "items error")
+	assert.Equal(t, 1, errs, "errors count error")
+}
+
+func TestSubscribeItemTypeError(t *testing.T) {
+	var errs []error
+	rxgo.Just(1, "two", 3).Subscribe(func(x int, err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	})
+	var te *rxgo.ItemTypeError
+	if assert.Equal(t, 1, len(errs), "errors count error") {
+		assert.True(t, errors.As(errs[0], &te), "not an ItemTypeError")
+	}
+
+	subscribers := map[string]func(ob *rxgo.Observable){
+		"func":  func(ob *rxgo.Observable) { ob.Subscribe(func(x int) {}) },
+		"funcs": func(ob *rxgo.Observable) { ob.SubscribeFuncs(func(x int) {}, nil, nil) },
+	}
+	for name, subscribe := range subscribers {
+		rxgo.VerifyNoLeaks(t, func() {
+			defer func() {
+				e, _ := recover().(error)
+				assert.True(t, errors.As(e, &te), name+": item of wrong type is dropped")
+			}()
+			subscribe(rxgo.Range(0, 1000).Map(func(x int) interface{} {
+				if x == 1 {
+					return "one"
+				}
+				return x
+			}))
+		})
+	}
+}
+
+func TestSubscribeBadFunc(t *testing.T) {
+	for _, f := range []interface{}{
+		func() {},
+		func(x int) int { return x },
+		func(x, y int) {},
+		func(x int, err error) error { return nil },
+		42,
+	} {
+		func() {
+			defer func() {
+				e, _ := recover().(error)
+				assert.True(t, errors.Is(e, rxgo.ErrFuncOnNext), fmt.Sprintf("%T accepted", f))
+			}()
+			rxgo.Just(1).Subscribe(f)
+		}()
+	}
+}
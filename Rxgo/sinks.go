@@ -0,0 +1,224 @@
+// Copyright 2018 The SS.SYSU Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rxgo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+)
+
+// Encoder writes an item to w
+type Encoder func(w io.Writer, x interface{}) error
+
+// LineEncoder writes an item formatted by fmt.Print in a line
+func LineEncoder(w io.Writer, x interface{}) error {
+	_, e := fmt.Fprintln(w, x)
+	return e
+}
+
+// sinkObserver passes items to next until an error flows or is returned by next
+type sinkObserver struct {
+	cancel context.CancelFunc
+	next   func(x interface{}) error
+	err    error
+}
+
+func (s *sinkObserver) OnNext(x interface{}) {
+	if s.err != nil {
+		return
+	}
+	if e := s.next(x); e != nil {
+		s.OnError(e)
+	}
+}
+
+func (s *sinkObserver) OnError(e error) {
+	if s.err == nil {
+		s.err = e
+		s.cancel()
+	}
+}
+
+func (s *sinkObserver) OnCompleted() {
+}
+
+// subscribe with next, it unsubscribes on the first error and returns it, or ctx.Err() if ctx is done
+func (o *Observable) sink(ctx context.Context, next func(x interface{}) error) error {
+	sctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	s := &sinkObserver{cancel: cancel, next: next}
+	o.observe(sctx, s)
+	if s.err == nil {
+		return ctx.Err()
+	}
+	return s.err
+}
+
+// ToWriter subscribes and writes items to w by enc, LineEncoder if enc is nil. It returns when completed
+// or on the first error flowed or returned by w, or ctx.Err() if ctx is done, and the error is returned after
+// buffered items are flushed to w.
+func (o *Observable) ToWriter(ctx context.Context, w io.Writer, enc Encoder) error {
+	if enc == nil {
+		enc = LineEncoder
+	}
+	bw := bufio.NewWriter(w)
+	e := o.sink(ctx, func(x interface{}) error {
+		return enc(bw, x)
+	})
+	if fe := bw.Flush(); e == nil {
+		e = fe
+	}
+	return e
+}
+
+// RotatePolicy of ToFile
+type RotatePolicy struct {
+	MaxBytes   int64 // rotate the file before it grows over MaxBytes, never rotated if not positive
+	MaxBackups int   // rotated files kept as path.1, path.2 and so on, the oldest is removed, at least 1
+}
+
+// ToFile subscribes and appends items to the file at path in lines by LineEncoder, rotating the file by policy.
+// An item is never split into two files. It returns like ToWriter, after the file is closed.
+func (o *Observable) ToFile(ctx context.Context, path string, policy RotatePolicy) error {
+	f := &rotateFile{path: path, policy: policy}
+	if e := f.open(); e != nil {
+		return e
+	}
+	var buf bytes.Buffer
+	e := o.sink(ctx, func(x interface{}) error {
+		buf.Reset()
+		if e := LineEncoder(&buf, x); e != nil {
+			return e
+		}
+		return f.write(buf.Bytes())
+	})
+	if ce := f.close(); e == nil {
+		e = ce
+	}
+	return e
+}
+
+// file written by ToFile
+type rotateFile struct {
+	path   string
+	policy RotatePolicy
+	f      *os.File
+	w      *bufio.Writer
+	size   int64
+}
+
+func (f *rotateFile) open() error {
+	file, e := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if e != nil {
+		return e
+	}
+	fi, e := file.Stat()
+	if e != nil {
+		file.Close()
+		return e
+	}
+	f.f, f.w, f.size = file, bufio.NewWriter(file), fi.Size()
+	return nil
+}
+
+// write an encoded item, rotate the file before if it would be too large
+func (f *rotateFile) write(p []byte) error {
+	if f.policy.MaxBytes > 0 && f.size > 0 && f.size+int64(len(p)) > f.policy.MaxBytes {
+		if e := f.rotate(); e != nil {
+			return e
+		}
+	}
+	n, e := f.w.Write(p)
+	f.size += int64(n)
+	return e
+}
+
+// rename path.N-1 to path.N ... path to path.1, and open a new file at path
+func (f *rotateFile) rotate() error {
+	if e := f.close(); e != nil {
+		return e
+	}
+	backup := func(i int) string {
+		if i == 0 {
+			return f.path
+		}
+		return fmt.Sprintf("%s.%d", f.path, i)
+	}
+	n := f.policy.MaxBackups
+	if n <= 0 {
+		n = 1
+	}
+	for i := n; i > 0; i-- {
+		if e := os.Rename(backup(i-1), backup(i)); e != nil && !os.IsNotExist(e) {
+			return e
+		}
+	}
+	return f.open()
+}
+
+func (f *rotateFile) close() error {
+	e := f.w.Flush()
+	if ce := f.f.Close(); e == nil {
+		e = ce
+	}
+	return e
+}
+
+// ToChannel subscribes and sends items to ch, a chan of any type, then closes ch. It is the inverse of From(ch).
+// It returns when completed or on the first error flowed, ItemTypeError if an item can not be sent to ch,
+// or ctx.Err() if ctx is done, also while blocked on sending to ch.
+func (o *Observable) ToChannel(ctx context.Context, ch interface{}) error {
+	cv := reflect.ValueOf(ch)
+	if cv.Kind() != reflect.Chan || cv.Type().ChanDir()&reflect.SendDir == 0 {
+		panic(ErrFuncFlip)
+	}
+	defer cv.Close()
+	t := cv.Type().Elem()
+	policy := o.stageCoercion(o.chainOptions())
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectSend, Chan: cv},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+	}
+	return o.sink(ctx, func(x interface{}) error {
+		xv, ok := coerceValue(reflect.ValueOf(x), t, policy)
+		if !ok {
+			return &ItemTypeError{Stage: "ToChannel", Item: x, Type: t}
+		}
+		cases[0].Send = xv
+		if chosen, _, _ := reflect.Select(cases); chosen == 1 {
+			return ctx.Err()
+		}
+		return nil
+	})
+}
+
+// Chan subscribes in a new goroutine and returns channels of items and the error. Items are sent until completed,
+// or the first error flowed which is then sent to the error channel, or ctx is done and ctx.Err() is sent.
+// Both channels are closed when the subscription is completed, so receive all items before the error.
+func (o *Observable) Chan(ctx context.Context) (<-chan interface{}, <-chan error) {
+	items := make(chan interface{})
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		defer close(items)
+		e := o.sink(ctx, func(x interface{}) error {
+			select {
+			case items <- x:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		if e != nil {
+			errs <- e
+		}
+	}()
+	return items, errs
+}
@@ -0,0 +1,102 @@
+package rxgo_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pmlpml/rxgo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToWriter(t *testing.T) {
+	var b bytes.Buffer
+	err := rxgo.Just(1, 2, 3).ToWriter(context.Background(), &b, nil)
+	assert.NoError(t, err, "write error")
+	assert.Equal(t, "1\n2\n3\n", b.String(), "written lines error")
+
+	b.Reset()
+	ee := errors.New("any")
+	err = rxgo.Just(1, 2, ee, 3).ToWriter(context.Background(), &b, func(w io.Writer, x interface{}) error {
+		_, e := fmt.Fprintf(w, "<%v>", x)
+		return e
+	})
+	assert.Equal(t, ee, err, "flowed error")
+	assert.Equal(t, "<1><2>", b.String(), "items before error are not flushed")
+}
+
+func TestToFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.log")
+	err := rxgo.Just("aaa", "bbb", "ccc", "ddd", "eee").ToFile(context.Background(), path, rxgo.RotatePolicy{MaxBytes: 8, MaxBackups: 1})
+	assert.NoError(t, err, "write file error")
+
+	current, _ := os.ReadFile(path)
+	backup, _ := os.ReadFile(path + ".1")
+	assert.Equal(t, "eee\n", string(current), "current file error")
+	assert.Equal(t, "ccc\nddd\n", string(backup), "backup file error")
+	_, err = os.Stat(path + ".2")
+	assert.True(t, os.IsNotExist(err), "too many backups")
+
+	// append
+	err = rxgo.Just("fff").ToFile(context.Background(), path, rxgo.RotatePolicy{})
+	assert.NoError(t, err, "append file error")
+	current, _ = os.ReadFile(path)
+	assert.Equal(t, "eee\nfff\n", string(current), "appended file error")
+
+	// rotated without backups configured, the last file is kept
+	err = rxgo.Just("ggg").ToFile(context.Background(), path, rxgo.RotatePolicy{MaxBytes: 8})
+	assert.NoError(t, err, "rotate file error")
+	current, _ = os.ReadFile(path)
+	backup, _ = os.ReadFile(path + ".1")
+	assert.Equal(t, "ggg\n", string(current), "current file error")
+	assert.Equal(t, "eee\nfff\n", string(backup), "current file lost on rotate")
+}
+
+func TestToChannel(t *testing.T) {
+	ch := make(chan int, 10)
+	err := rxgo.Range(0, 3).ToChannel(context.Background(), ch)
+	assert.NoError(t, err, "send error")
+	res := []int{}
+	for x := range ch {
+		res = append(res, x)
+	}
+	assert.Equal(t, []int{0, 1, 2}, res, "channel items error")
+
+	ch = make(chan int, 10)
+	err = rxgo.Just(1, "two", 3).ToChannel(context.Background(), ch)
+	var te *rxgo.ItemTypeError
+	assert.True(t, errors.As(err, &te), "not an ItemTypeError")
+	_, ok := <-ch
+	assert.True(t, ok, "item before error lost")
+	_, ok = <-ch
+	assert.False(t, ok, "channel not closed")
+
+	// nobody receives
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = rxgo.Range(0, 3).ToChannel(ctx, make(chan int))
+	assert.Equal(t, context.DeadlineExceeded, err, "blocked send not canceled")
+}
+
+func TestChan(t *testing.T) {
+	items, errs := rxgo.Range(0, 3).Chan(context.Background())
+	res := []interface{}{}
+	for x := range items {
+		res = append(res, x)
+	}
+	assert.Equal(t, []interface{}{0, 1, 2}, res, "channel items error")
+	assert.NoError(t, <-errs, "channel error")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	items, errs = rxgo.Never().Chan(ctx)
+	cancel()
+	_, ok := <-items
+	assert.False(t, ok, "items not closed")
+	assert.Equal(t, context.Canceled, <-errs, "canceled error")
+}
@@ -0,0 +1,152 @@
+// Copyright 2018 The SS.SYSU Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rxgo
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SlogObserver logs items, errors and completion of a stage as structured records.
+// It can be used as a monitor of a stage by SetLogger or SetMonitor, and then sequence numbers
+// and elapsed time are counted for each subscription.
+type SlogObserver struct {
+	logger         *slog.Logger
+	stage          string // name of the monitored stage if empty
+	itemLevel      slog.Level
+	errorLevel     slog.Level
+	completedLevel slog.Level
+	sampleEvery    uint64 // log one of every n items
+
+	seq       atomic.Uint64 // sequence number of items and errors
+	startOnce sync.Once
+	start     time.Time
+}
+
+var _ Observer = (*SlogObserver)(nil)
+
+// SlogOption sets a field of SlogObserver
+type SlogOption func(*SlogObserver)
+
+// SlogItemLevel sets level of item records, default is slog.LevelDebug
+func SlogItemLevel(l slog.Level) SlogOption {
+	return func(so *SlogObserver) {
+		so.itemLevel = l
+	}
+}
+
+// SlogErrorLevel sets level of error records, default is slog.LevelError
+func SlogErrorLevel(l slog.Level) SlogOption {
+	return func(so *SlogObserver) {
+		so.errorLevel = l
+	}
+}
+
+// SlogCompletedLevel sets level of completion records, default is slog.LevelInfo
+func SlogCompletedLevel(l slog.Level) SlogOption {
+	return func(so *SlogObserver) {
+		so.completedLevel = l
+	}
+}
+
+// SlogSampleEvery logs one of every n items. Errors and completion are always logged.
+func SlogSampleEvery(n uint64) SlogOption {
+	return func(so *SlogObserver) {
+		so.sampleEvery = n
+	}
+}
+
+// NewSlogObserver creates a SlogObserver for the stage, the name of the monitored stage is used if stage is empty.
+// slog.Default() is used if logger is nil.
+func NewSlogObserver(logger *slog.Logger, stage string, opts ...SlogOption) *SlogObserver {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	so := &SlogObserver{
+		logger:         logger,
+		stage:          stage,
+		itemLevel:      slog.LevelDebug,
+		errorLevel:     slog.LevelError,
+		completedLevel: slog.LevelInfo,
+		sampleEvery:    1,
+	}
+	for _, opt := range opts {
+		opt(so)
+	}
+	return so
+}
+
+// a SlogObserver of the same settings with new counters for a subscription of the stage
+func (so *SlogObserver) subscribe(stage string) Observer {
+	if so.stage != "" {
+		stage = so.stage
+	}
+	return &SlogObserver{
+		logger:         so.logger,
+		stage:          stage,
+		itemLevel:      so.itemLevel,
+		errorLevel:     so.errorLevel,
+		completedLevel: so.completedLevel,
+		sampleEvery:    so.sampleEvery,
+	}
+}
+
+// time since the first event
+func (so *SlogObserver) elapsed() time.Duration {
+	so.startOnce.Do(func() {
+		so.start = time.Now()
+	})
+	return time.Since(so.start)
+}
+
+func (so *SlogObserver) OnNext(x interface{}) {
+	seq := so.seq.Add(1)
+	elapsed := so.elapsed()
+	if so.sampleEvery > 1 && (seq-1)%so.sampleEvery != 0 {
+		return
+	}
+	if !so.logger.Enabled(context.Background(), so.itemLevel) {
+		return
+	}
+	so.logger.LogAttrs(context.Background(), so.itemLevel, "rxgo item",
+		slog.String("stage", so.stage),
+		slog.Uint64("seq", seq),
+		slog.Duration("elapsed", elapsed),
+		slog.Any("item", x))
+}
+
+func (so *SlogObserver) OnError(e error) {
+	seq := so.seq.Add(1)
+	elapsed := so.elapsed()
+	if !so.logger.Enabled(context.Background(), so.errorLevel) {
+		return
+	}
+	so.logger.LogAttrs(context.Background(), so.errorLevel, "rxgo error",
+		slog.String("stage", so.stage),
+		slog.Uint64("seq", seq),
+		slog.Duration("elapsed", elapsed),
+		slog.Any("error", e))
+}
+
+func (so *SlogObserver) OnCompleted() {
+	elapsed := so.elapsed()
+	if !so.logger.Enabled(context.Background(), so.completedLevel) {
+		return
+	}
+	so.logger.LogAttrs(context.Background(), so.completedLevel, "rxgo completed",
+		slog.String("stage", so.stage),
+		slog.Uint64("count", so.seq.Load()),
+		slog.Duration("elapsed", elapsed))
+}
+
+// SetLogger logs items, errors and completion of this stage with logger, the stage is named
+// by options of the subscribed chain
+func (o *Observable) SetLogger(logger *slog.Logger, opts ...SlogOption) *Observable {
+	o.debug = NewSlogObserver(logger, "", opts...)
+	return o
+}
@@ -0,0 +1,92 @@
+package rxgo_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/pmlpml/rxgo"
+	"github.com/stretchr/testify/assert"
+)
+
+func readRecords(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	records := []map[string]interface{}{}
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		r := map[string]interface{}{}
+		if err := dec.Decode(&r); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+func TestSetLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	rxgo.Just(1, errors.New("any"), 2).Map(func(x int) int {
+		return x
+	}).SetLogger(logger).Subscribe(func(x int) {})
+
+	records := readRecords(t, &buf)
+	msgs := []string{}
+	for _, r := range records {
+		msgs = append(msgs, r["msg"].(string))
+		assert.Equal(t, "map", r["stage"], "stage attr error")
+	}
+	assert.Equal(t, []string{"rxgo item", "rxgo error", "rxgo item", "rxgo completed"}, msgs, "records error")
+	assert.Equal(t, float64(1), records[0]["item"], "item attr error")
+	assert.Equal(t, float64(3), records[2]["seq"], "seq attr error")
+	assert.Equal(t, "ERROR", records[1]["level"], "error level error")
+	assert.Equal(t, float64(3), records[3]["count"], "count attr error")
+}
+
+func TestSlogSampleAndLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	// items in debug level are disabled
+	rxgo.Range(0, 10).SetLogger(logger).Subscribe(func(x int) {})
+	records := readRecords(t, &buf)
+	assert.Equal(t, 1, len(records), "debug items should not be logged")
+
+	rxgo.Range(0, 10).SetLogger(logger,
+		rxgo.SlogItemLevel(slog.LevelInfo),
+		rxgo.SlogSampleEvery(4),
+		rxgo.SlogCompletedLevel(slog.LevelDebug),
+	).Subscribe(func(x int) {})
+	items := []float64{}
+	for _, r := range readRecords(t, &buf) {
+		items = append(items, r["item"].(float64))
+	}
+	assert.Equal(t, []float64{0, 4, 8}, items, "sample error")
+}
+
+func TestSlogSubscriptions(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	// named by options configured after SetLogger
+	ob := rxgo.Range(0, 3).Map(func(x int) int {
+		return x
+	}).SetLogger(logger).Configure(rxgo.WithName("io"))
+	ob.Subscribe(func(x int) {})
+	ob.Subscribe(func(x int) {})
+
+	seqs := []float64{}
+	counts := []float64{}
+	for _, r := range readRecords(t, &buf) {
+		assert.Equal(t, "io/map", r["stage"], "stage attr error")
+		if seq, ok := r["seq"]; ok {
+			seqs = append(seqs, seq.(float64))
+		} else {
+			counts = append(counts, r["count"].(float64))
+		}
+	}
+	assert.Equal(t, []float64{1, 2, 3, 1, 2, 3}, seqs, "seq is not counted per subscription")
+	assert.Equal(t, []float64{3, 3}, counts, "count is not counted per subscription")
+}
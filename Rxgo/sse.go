@@ -0,0 +1,232 @@
+// Copyright 2018 The SS.SYSU Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rxgo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ErrNotEventStream is sent by FromSSE if the response is not a successful text/event-stream
+var ErrNotEventStream = errors.New("rxgo: response is not an event stream")
+
+// max size of a line read by FromSSE, lines of event streams are not limited by the spec
+const maxSSELineSize = 16 << 20
+
+// SSEEvent is an event of server-sent events
+type SSEEvent struct {
+	ID    string // last event ID, empty if not set
+	Event string // event type, "message" if empty
+	Data  string // lines of data joined by "\n"
+	Retry int    // reconnection time in milliseconds, 0 if not set
+}
+
+// write the event in text/event-stream format
+func (ev *SSEEvent) writeTo(w io.Writer) error {
+	var b bytes.Buffer
+	if ev.ID != "" {
+		fmt.Fprintf(&b, "id: %s\n", ev.ID)
+	}
+	if ev.Event != "" {
+		fmt.Fprintf(&b, "event: %s\n", ev.Event)
+	}
+	if ev.Retry > 0 {
+		fmt.Fprintf(&b, "retry: %d\n", ev.Retry)
+	}
+	for _, l := range strings.Split(ev.Data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", l)
+	}
+	b.WriteByte('\n')
+	_, e := w.Write(b.Bytes())
+	return e
+}
+
+// FromSSE creates an Observable of SSEEvent parsed from the text/event-stream body of resp. Comments and events
+// without data are skipped. The body is closed on completion or unsubscription, and ErrNotEventStream or a read error
+// is sent before completion. The body can be read only once, so the Observable is subscribed once.
+func FromSSE(resp *http.Response) *Observable {
+	o := newGeneratorObservable("FromSSE")
+
+	o.flip = func(ctx context.Context, out *flow) {
+		defer watchReader(ctx, resp.Body)()
+		mt, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+		if resp.StatusCode != http.StatusOK || mt != "text/event-stream" {
+			o.sendToFlow(ctx, fmt.Errorf("%w: %s %s", ErrNotEventStream, resp.Status, mt), out)
+			return
+		}
+		sc := bufio.NewScanner(resp.Body)
+		sc.Split(scanSSELines)
+		sc.Buffer(nil, maxSSELineSize)
+		var ev SSEEvent
+		var data strings.Builder
+		first := true
+		for sc.Scan() {
+			line := sc.Text()
+			if first {
+				// a leading BOM of the stream is dropped
+				line, first = strings.TrimPrefix(line, "\uFEFF"), false
+			}
+			if line == "" {
+				if data.Len() > 0 {
+					ev.Data = strings.TrimSuffix(data.String(), "\n")
+					if b := o.sendToFlow(ctx, ev, out); b {
+						return
+					}
+				}
+				// the last event ID is kept by following events
+				ev = SSEEvent{ID: ev.ID, Retry: ev.Retry}
+				data.Reset()
+				continue
+			}
+			field, value := line, ""
+			if i := strings.IndexByte(line, ':'); i >= 0 {
+				field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+			}
+			switch field {
+			case "data":
+				data.WriteString(value)
+				data.WriteByte('\n')
+			case "event":
+				ev.Event = value
+			case "id":
+				if !strings.ContainsRune(value, 0) {
+					ev.ID = value
+				}
+			case "retry":
+				if n, e := strconv.Atoi(value); e == nil && n >= 0 {
+					ev.Retry = n
+				}
+			}
+		}
+		if e := sc.Err(); e != nil && ctx.Err() == nil {
+			o.sendToFlow(ctx, e, out)
+		}
+	}
+	o.operator = flipSource
+	return o
+}
+
+// scanSSELines splits lines of an event stream ended by "\r\n", "\n" or "\r"
+func scanSSELines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\n' {
+			return i + 1, data[:i], nil
+		}
+		if i+1 < len(data) {
+			if data[i+1] == '\n' {
+				return i + 2, data[:i], nil
+			}
+			return i + 1, data[:i], nil
+		}
+		// a "\n" may follow the "\r" at the end of data
+		if atEOF {
+			return i + 1, data[:i], nil
+		}
+		return 0, nil, nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// ServeSSE creates a handler streaming o to each client as server-sent events, and the response is flushed per event.
+// SSEEvent items are sent as they are, and other items are sent as data of events encoded by enc, LineEncoder
+// if enc is nil. The subscription is cancelled when the client disconnects, and the stream ends with an event of
+// type "error" on the first error flowed or returned by enc.
+func ServeSSE(o *Observable, enc Encoder) http.Handler {
+	if enc == nil {
+		enc = LineEncoder
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fl, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+		h := w.Header()
+		h.Set("Content-Type", "text/event-stream")
+		h.Set("Cache-Control", "no-cache")
+		h.Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		fl.Flush()
+
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		o.Subscribe(&sseObserver{ctx: ctx, cancel: cancel, w: w, fl: fl, enc: enc})
+	})
+}
+
+// sseObserver writes items to a client of ServeSSE
+type sseObserver struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	w      io.Writer
+	fl     http.Flusher
+	enc    Encoder
+	buf    bytes.Buffer
+	done   bool
+}
+
+func (s *sseObserver) OnNext(x interface{}) {
+	if s.done {
+		return
+	}
+	var ev *SSEEvent
+	switch e := x.(type) {
+	case SSEEvent:
+		ev = &e
+	case *SSEEvent:
+		ev = e
+	default:
+		s.buf.Reset()
+		if err := s.enc(&s.buf, x); err != nil {
+			s.OnError(err)
+			return
+		}
+		ev = &SSEEvent{Data: strings.TrimSuffix(s.buf.String(), "\n")}
+	}
+	s.write(ev)
+}
+
+func (s *sseObserver) OnError(e error) {
+	if !s.done {
+		s.write(&SSEEvent{Event: "error", Data: e.Error()})
+		s.done = true
+		s.cancel()
+	}
+}
+
+// write and flush an event, the subscription is cancelled if the client is gone
+func (s *sseObserver) write(ev *SSEEvent) {
+	if e := ev.writeTo(s.w); e != nil {
+		s.done = true
+		s.cancel()
+		return
+	}
+	s.fl.Flush()
+}
+
+func (s *sseObserver) OnCompleted() {
+}
+
+func (s *sseObserver) GetObserverContext() context.Context {
+	return s.ctx
+}
+
+func (s *sseObserver) OnConnected() {
+}
+
+func (s *sseObserver) Unsubscribe() {
+	s.cancel()
+}
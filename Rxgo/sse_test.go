@@ -0,0 +1,120 @@
+package rxgo_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pmlpml/rxgo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromSSE(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+		io.WriteString(w, ": comment\n\ndata: a\ndata: b\n\nid: 7\nevent: tick\nretry: 500\ndata:c\n\nevent: empty\n\ndata: d\n\ndata: partial\n")
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if !assert.NoError(t, err, "get error") {
+		return
+	}
+	res := []interface{}{}
+	rxgo.FromSSE(resp).Subscribe(collectItems(&res))
+	assert.Equal(t, []interface{}{
+		rxgo.SSEEvent{Data: "a\nb"},
+		rxgo.SSEEvent{ID: "7", Event: "tick", Data: "c", Retry: 500},
+		rxgo.SSEEvent{ID: "7", Data: "d", Retry: 500},
+	}, res, "parsed events error")
+
+	resp, _ = http.Get(srv.URL + "/none")
+	resp.Header.Set("Content-Type", "text/plain")
+	res = res[:0]
+	rxgo.FromSSE(resp).Subscribe(collectItems(&res))
+	if assert.Equal(t, 1, len(res), "not event stream") {
+		assert.True(t, errors.Is(res[0].(error), rxgo.ErrNotEventStream), "not event stream error")
+	}
+}
+
+func TestFromSSELines(t *testing.T) {
+	long := strings.Repeat("x", 100*1024)
+	body := "\uFEFFdata: a\r\rdata: b\r\ndata: c\r\n\r\nid: 1\ndata: " + long + "\n\n"
+	resp := &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"text/event-stream"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+	res := []interface{}{}
+	rxgo.FromSSE(resp).Subscribe(collectItems(&res))
+	assert.Equal(t, []interface{}{
+		rxgo.SSEEvent{Data: "a"},
+		rxgo.SSEEvent{Data: "b\nc"},
+		rxgo.SSEEvent{ID: "1", Data: long},
+	}, res, "parsed events error")
+}
+
+func TestServeSSE(t *testing.T) {
+	ee := errors.New("broken")
+	ob := rxgo.Just(map[string]int{"x": 1}, rxgo.SSEEvent{ID: "2", Event: "tick", Data: "two\nlines"}, ee, 3)
+	srv := httptest.NewServer(rxgo.ServeSSE(ob, func(w io.Writer, x interface{}) error {
+		return json.NewEncoder(w).Encode(x)
+	}))
+	defer srv.Close()
+
+	for i := 0; i < 2; i++ {
+		resp, err := http.Get(srv.URL)
+		if !assert.NoError(t, err, "get error") {
+			return
+		}
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"), "content type error")
+		res := []interface{}{}
+		rxgo.FromSSE(resp).Subscribe(collectItems(&res))
+		assert.Equal(t, []interface{}{
+			rxgo.SSEEvent{Data: `{"x":1}`},
+			rxgo.SSEEvent{ID: "2", Event: "tick", Data: "two\nlines"},
+			rxgo.SSEEvent{ID: "2", Event: "error", Data: "broken"},
+		}, res, "served events error")
+	}
+}
+
+func TestServeSSEDisconnect(t *testing.T) {
+	done := make(chan struct{})
+	h := rxgo.ServeSSE(rxgo.Range(0, 1<<30), nil)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(done)
+		h.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", srv.URL, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.NoError(t, err, "get error") {
+		return
+	}
+	res := []string{}
+	rxgo.FromSSE(resp).Subscribe(func(ev rxgo.SSEEvent) {
+		if len(res) < 3 {
+			res = append(res, ev.Data)
+		}
+		if len(res) == 3 {
+			cancel()
+		}
+	})
+	assert.Equal(t, "0,1,2", strings.Join(res, ","), "first events error")
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Error("handler is not cancelled after the client disconnected")
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2018 The SS.SYSU Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rxgo
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// SpanContext identifies a span of an item in a trace
+type SpanContext struct {
+	TraceID string
+	SpanID  string
+}
+
+// IsValid reports whether the span context is set
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != "" && sc.SpanID != ""
+}
+
+// Span records an item processed by a stage
+type Span struct {
+	SpanContext
+	ParentID string // span id of the stage which sent the item, empty for a source span
+	Name     string // stage name
+	Start    time.Time
+	End      time.Time
+	Item     interface{} // item received by the stage, or sent by the source
+}
+
+// SpanExporter receives finished spans. It is called by many goroutines at the same time.
+type SpanExporter interface {
+	ExportSpan(span Span)
+}
+
+// InMemoryExporter keeps all spans in memory, mostly for testing
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []Span
+}
+
+var _ SpanExporter = (*InMemoryExporter)(nil)
+
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{}
+}
+
+func (e *InMemoryExporter) ExportSpan(span Span) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, span)
+}
+
+// Spans returns a copy of exported spans in order of finishing
+func (e *InMemoryExporter) Spans() []Span {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]Span(nil), e.spans...)
+}
+
+// Reset drops all exported spans
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = nil
+}
+
+// WithTracing traces each item through stages of the chain and exports spans to exp
+func WithTracing(exp SpanExporter) Option {
+	return func(op *Options) {
+		op.Tracing = exp
+	}
+}
+
+type spanContextKey struct{}
+
+// ContextWithSpanContext returns a copy of ctx carrying sc
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// SpanContextFromContext gets span context of current item from the context passed to user function `func(ctx context.Context, x anytype)`
+func SpanContextFromContext(ctx context.Context) (sc SpanContext, ok bool) {
+	sc, ok = ctx.Value(spanContextKey{}).(SpanContext)
+	return
+}
+
+// an item with span context in flow
+type tracedItem struct {
+	item interface{}
+	sc   SpanContext
+}
+
+// receive an item from flow, restore the span context carried by it
+func recvItem(ctx context.Context, x interface{}) (context.Context, interface{}) {
+	if ti, ok := x.(tracedItem); ok {
+		return ContextWithSpanContext(ctx, ti.sc), ti.item
+	}
+	return ctx, x
+}
+
+// get exporter of the chain connected with ctx, nil if not configured
+func tracingOf(ctx context.Context) SpanExporter {
+	if op := optionsOf(ctx); op != nil {
+		return op.Tracing
+	}
+	return nil
+}
+
+// attach span context in ctx to the item before sending in a chain of options op. A source creates a new trace for each item.
+func (o *Observable) traceItem(ctx context.Context, op *Options, item interface{}) interface{} {
+	if op == nil || op.Tracing == nil {
+		return item
+	}
+	exp := op.Tracing
+	sc, ok := SpanContextFromContext(ctx)
+	if !ok && o.pred == nil {
+		now := time.Now()
+		sc = SpanContext{TraceID: newTraceID(), SpanID: newSpanID()}
+		exp.ExportSpan(Span{SpanContext: sc, Name: o.stageName(op), Start: now, End: now, Item: item})
+		ok = true
+	}
+	if !ok {
+		return item
+	}
+	return tracedItem{item, sc}
+}
+
+// start a span for the item processing by the stage, call finish when processed
+func (o *Observable) startSpan(ctx context.Context, item interface{}) (sctx context.Context, finish func()) {
+	exp := tracingOf(ctx)
+	if exp == nil {
+		return ctx, func() {}
+	}
+	span := Span{Name: o.stageName(optionsOf(ctx)), Start: time.Now(), Item: item}
+	if parent, ok := SpanContextFromContext(ctx); ok {
+		span.TraceID = parent.TraceID
+		span.ParentID = parent.SpanID
+	} else {
+		span.TraceID = newTraceID()
+	}
+	span.SpanID = newSpanID()
+	return ContextWithSpanContext(ctx, span.SpanContext), func() {
+		span.End = time.Now()
+		exp.ExportSpan(span)
+	}
+}
+
+func newTraceID() string {
+	return randomHex(16)
+}
+
+func newSpanID() string {
+	return randomHex(8)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,107 @@
+package rxgo_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/pmlpml/rxgo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracing(t *testing.T) {
+	exp := rxgo.NewInMemoryExporter()
+	seen := map[int]rxgo.SpanContext{}
+	res := []int{}
+
+	rxgo.Just(1, 2, 3).Map(func(ctx context.Context, x int) int {
+		sc, ok := rxgo.SpanContextFromContext(ctx)
+		assert.True(t, ok, "no span in context")
+		seen[x] = sc
+		return 10 * x
+	}).Filter(func(x int) bool {
+		return x != 20
+	}).Configure(rxgo.WithTracing(exp)).Subscribe(func(x int) {
+		res = append(res, x)
+	})
+	assert.Equal(t, []int{10, 30}, res, "tracing changes data")
+
+	spans := exp.Spans()
+	assert.Equal(t, 9, len(spans), "span count error")
+
+	byID := map[string]rxgo.Span{}
+	for _, s := range spans {
+		byID[s.SpanID] = s
+	}
+	for _, s := range spans {
+		if s.Name != "filter" {
+			continue
+		}
+		mapSpan := byID[s.ParentID]
+		assert.Equal(t, "map", mapSpan.Name, "parent of filter error")
+		assert.Equal(t, s.TraceID, mapSpan.TraceID, "trace id error")
+		assert.Equal(t, seen[mapSpan.Item.(int)], mapSpan.SpanContext, "span context of user function error")
+
+		source := byID[mapSpan.ParentID]
+		assert.Equal(t, "Just", source.Name, "parent of map error")
+		assert.Equal(t, "", source.ParentID, "source span has parent")
+		assert.Equal(t, mapSpan.Item, source.Item, "source item error")
+	}
+}
+
+func TestTracingFlatMap(t *testing.T) {
+	exp := rxgo.NewInMemoryExporter()
+	var mu sync.Mutex
+	traces := map[string]int{}
+
+	rxgo.Just(1, 2).FlatMap(func(x int) *rxgo.Observable {
+		return rxgo.Just(x, x)
+	}).Map(func(ctx context.Context, x int) int {
+		sc, _ := rxgo.SpanContextFromContext(ctx)
+		mu.Lock()
+		traces[sc.TraceID]++
+		mu.Unlock()
+		return x
+	}).Configure(rxgo.WithTracing(exp)).Subscribe(func(x int) {})
+
+	assert.Equal(t, 2, len(traces), "items of an inner observable must be in one trace")
+	for _, n := range traces {
+		assert.Equal(t, 2, n, "trace item count error")
+	}
+	assert.Equal(t, 8, len(exp.Spans()), "span count error")
+}
+
+func TestTracingFilters(t *testing.T) {
+	filters := map[string]func(o *rxgo.Observable) *rxgo.Observable{
+		"distinct":  func(o *rxgo.Observable) *rxgo.Observable { return o.Distinct() },
+		"take":      func(o *rxgo.Observable) *rxgo.Observable { return o.Take(2) },
+		"skip":      func(o *rxgo.Observable) *rxgo.Observable { return o.Skip(1) },
+		"first":     func(o *rxgo.Observable) *rxgo.Observable { return o.First() },
+		"last":      func(o *rxgo.Observable) *rxgo.Observable { return o.Last() },
+		"elementAt": func(o *rxgo.Observable) *rxgo.Observable { return o.ElementAt(2) },
+	}
+	for name, filter := range filters {
+		exp := rxgo.NewInMemoryExporter()
+		var mu sync.Mutex
+		traces := map[int]string{}
+		filter(rxgo.Just(1, 2, 2, 3)).Map(func(ctx context.Context, x int) int {
+			sc, ok := rxgo.SpanContextFromContext(ctx)
+			assert.True(t, ok, name+": no span in context")
+			mu.Lock()
+			traces[x] = sc.TraceID
+			mu.Unlock()
+			return x
+		}).Configure(rxgo.WithTracing(exp)).Subscribe(func(x int) {})
+
+		sources := map[string]int{}
+		for _, s := range exp.Spans() {
+			if s.Name == "Just" {
+				sources[s.TraceID] = s.Item.(int)
+			}
+		}
+		assert.True(t, len(traces) > 0, name+": no items")
+		for x, id := range traces {
+			assert.Equal(t, x, sources[id], name+": trace is broken by the filter")
+		}
+	}
+}
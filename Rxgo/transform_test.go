@@ -97,3 +97,185 @@ func TestFilter(t *testing.T) {
 
 	assert.Equal(t, []int{0, 7, 2}, res, "Map Test Error!")
 }
+
+func TestMapWithError(t *testing.T) {
+	res := []interface{}{}
+	ee := errors.New("Any")
+	rxgo.Just(10, 20, 30, 40, 50).Map(func(ctx context.Context, x int) (int, error) {
+		switch x {
+		case 20:
+			return 0, rxgo.ErrSkipItem
+		case 30:
+			return 0, ee
+		case 50:
+			return 0, rxgo.ErrEoFlow
+		}
+		return 2 * x, nil
+	}).Subscribe(rxgo.ObserverMonitor{
+		Next: func(item interface{}) {
+			res = append(res, item)
+		},
+		Error: func(e error) {
+			res = append(res, e)
+		},
+	})
+
+	assert.Equal(t, []interface{}{20, ee, 80}, res, "Map with error Test Error!")
+}
+
+func TestFlatMapWithError(t *testing.T) {
+	res := []int{}
+	rxgo.Just(10, 20, 30).FlatMap(func(x int) (*rxgo.Observable, error) {
+		if x == 20 {
+			return nil, rxgo.ErrSkipItem
+		}
+		return rxgo.Just(x+1, x+2), nil
+	}).Subscribe(func(x int) {
+		res = append(res, x)
+	})
+
+	assert.Equal(t, []int{11, 12, 31, 32}, res, "FlatMap with error Test Error!")
+}
+
+func TestFilterWithError(t *testing.T) {
+	res := []int{}
+	errs := 0
+	rxgo.Just(0, 12, 7, 34, 2).Filter(func(x int) (bool, error) {
+		if x == 34 {
+			return false, errors.New("Any")
+		}
+		return x < 10, nil
+	}).Subscribe(rxgo.ObserverMonitor{
+		Next: func(item interface{}) {
+			res = append(res, item.(int))
+		},
+		Error: func(e error) {
+			errs++
+		},
+	})
+
+	assert.Equal(t, []int{0, 7, 2}, res, "Filter with error Test Error!")
+	assert.Equal(t, 1, errs, "Filter with error count Error!")
+}
+
+func TestFuncWithBadError(t *testing.T) {
+	defer func() {
+		assert.Equal(t, rxgo.ErrFuncFlip, recover(), "bad signature accepted")
+	}()
+	rxgo.Just(1).Map(func(x int) (int, int) {
+		return x, x
+	})
+}
+
+func TestCoercion(t *testing.T) {
+	double := func(x int64) int64 {
+		return 2 * x
+	}
+
+	var errs []error
+	res := []interface{}{}
+	rxgo.Just(1, int64(2)).Map(double).Subscribe(rxgo.ObserverMonitor{
+		Next: func(x interface{}) {
+			res = append(res, x)
+		},
+		Error: func(e error) {
+			errs = append(errs, e)
+		},
+	})
+	assert.Equal(t, []interface{}{int64(4)}, res, "assignable items error")
+	assert.Equal(t, 1, len(errs), "errors count error")
+	var te *rxgo.ItemTypeError
+	assert.True(t, errors.As(errs[0], &te), "not an ItemTypeError")
+	assert.Equal(t, "map", te.Stage, "stage error")
+	assert.Equal(t, 1, te.Item, "item error")
+	assert.Equal(t, "map can not pass item of type int to parameter of type int64", te.Error(), "message error")
+
+	res = []interface{}{}
+	rxgo.Just(1, int64(2), 3.0).Map(double).Configure(rxgo.WithCoercion(rxgo.CoercionConvertible)).Subscribe(func(x int64) {
+		res = append(res, x)
+	})
+	assert.Equal(t, []interface{}{int64(2), int64(4), int64(6)}, res, "convertible items error")
+
+	type myInt int
+	errs = nil
+	rxgo.Just(myInt(1)).Map(func(x int) int {
+		return x
+	}).Configure(rxgo.WithCoercion(rxgo.CoercionConvertible)).Subscribe(rxgo.ObserverMonitor{
+		Error: func(e error) {
+			errs = append(errs, e)
+		},
+	})
+	assert.Equal(t, 0, len(errs), "named number not converted")
+
+	// lossy conversions
+	errs = nil
+	res = []interface{}{}
+	rxgo.Just(1.9, -1, 300, 2.0, uint64(1<<63)).Map(func(x uint8) uint8 {
+		return x
+	}).Configure(rxgo.WithCoercion(rxgo.CoercionConvertible)).Subscribe(rxgo.ObserverMonitor{
+		Next: func(x interface{}) {
+			res = append(res, x)
+		},
+		Error: func(e error) {
+			errs = append(errs, e)
+		},
+	})
+	assert.Equal(t, []interface{}{uint8(2)}, res, "lossless items error")
+	assert.Equal(t, 4, len(errs), "lossy items converted")
+	assert.True(t, errors.As(errs[0], &te), "not an ItemTypeError")
+	res = []interface{}{}
+	rxgo.Just(uint64(1<<63), -1).Map(func(x int64) int64 {
+		return x
+	}).Configure(rxgo.WithCoercion(rxgo.CoercionConvertible)).Subscribe(collectItems(&res))
+	assert.Equal(t, 2, len(res), "signed items error")
+	assert.True(t, errors.As(res[0].(error), &te), "wrapped item converted")
+	assert.Equal(t, int64(-1), res[1], "negative item error")
+
+	type ints []int
+	count := func(c rxgo.Coercion) (n int) {
+		rxgo.Just(ints{1}).Filter(func(x []int) bool {
+			return true
+		}).Configure(rxgo.WithCoercion(c)).Subscribe(rxgo.ObserverMonitor{
+			Error: func(e error) {
+				n++
+			},
+		})
+		return
+	}
+	assert.Equal(t, 0, count(rxgo.CoercionAssignable), "assignable slice error")
+	assert.Equal(t, 1, count(rxgo.CoercionStrict), "strict slice error")
+}
+
+func TestNilItems(t *testing.T) {
+	for _, exp := range []rxgo.SpanExporter{nil, rxgo.NewInMemoryExporter()} {
+		configure := func(ob *rxgo.Observable) *rxgo.Observable {
+			if exp != nil {
+				ob = ob.Configure(rxgo.WithTracing(exp))
+			}
+			return ob
+		}
+		var errs []error
+		res := []interface{}{}
+		configure(rxgo.Just(nil, 1).Map(func(x int) int {
+			return x + 1
+		})).Subscribe(rxgo.ObserverMonitor{
+			Next:  func(x interface{}) { res = append(res, x) },
+			Error: func(e error) { errs = append(errs, e) },
+		})
+		assert.Equal(t, []interface{}{2}, res, "items after nil error")
+		var te *rxgo.ItemTypeError
+		if assert.Equal(t, 1, len(errs), "nil item to int") && assert.True(t, errors.As(errs[0], &te), "not an ItemTypeError") {
+			assert.Equal(t, nil, te.Item, "item error")
+		}
+
+		res = res[:0]
+		configure(rxgo.Just(nil, "a").Map(func(x *string) bool {
+			return x == nil
+		}).Filter(func(x interface{}) bool {
+			return true
+		})).Subscribe(func(x interface{}) {
+			res = append(res, x)
+		})
+		assert.Equal(t, []interface{}{true}, res, "nil item to pointer error")
+	}
+}
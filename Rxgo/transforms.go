@@ -7,7 +7,6 @@ package rxgo
 import (
 	"context"
 	"reflect"
-	"sync"
 )
 
 var (
@@ -20,53 +19,54 @@ var (
 
 // transform node implementation of streamOperator
 type transOperater struct {
-	opFunc func(ctx context.Context, o *Observable, item reflect.Value, out chan interface{}) (end bool)
+	opFunc func(ctx context.Context, o *Observable, item reflect.Value, out *flow) (end bool)
 }
 
-func (tsop transOperater) op(ctx context.Context, o *Observable) {
-	// must hold defintion of flow resourcs here, such as chan etc., that is allocated when connected
-	// this resurces may be changed when operation routine is running.
-	in := o.pred.outflow
-	out := o.outflow
+func (tsop transOperater) op(ctx context.Context, o *Observable, in, out *flow) {
+	// flow resourcs, such as chan etc., are allocated for each subscription when connected
 	//fmt.Println(o.name, "operator in/out chan ", in, out)
-	var wg sync.WaitGroup
+	sch := newScheduler(ctx, o)
 
-	go func() {
-		end := false
-		for x := range in {
-			if end {
-				continue
+	o.receive(ctx, in, out, &receiver{
+		next: func(x interface{}) (stop bool) {
+			if sch.ended() {
+				return true
 			}
+			ictx, x := recvItem(ctx, x)
 			// can not pass a interface as parameter (pointer) to gorountion for it may change its value outside!
 			xv := reflect.ValueOf(x)
 			// send an error to stream if the flip not accept error
 			if e, ok := x.(error); ok && !o.flip_accept_error {
-				o.sendToFlow(ctx, e, out)
-				continue
+				o.sendToFlow(ictx, e, out)
+				return false
 			}
-			// scheduler
-			switch threading := o.threading; threading {
-			case ThreadingDefault:
-				if tsop.opFunc(ctx, o, xv, out) {
-					end = true
+			// scheduler, an item of ThreadingDefault is processed by the receiving goroutine without a closure
+			if sch.threading == ThreadingDefault {
+				if tsop.traceOp(ictx, o, xv, out) {
+					sch.end.Store(true)
 				}
-			case ThreadingIO:
-				fallthrough
-			case ThreadingComputing:
-				wg.Add(1)
-				go func() {
-					defer wg.Done()
-					if tsop.opFunc(ctx, o, xv, out) {
-						end = true
-					}
-				}()
-			default:
+				return sch.ended()
 			}
-		}
+			sch.run(func() bool {
+				return tsop.traceOp(ictx, o, xv, out)
+			})
+			return sch.ended()
+		},
+		complete: func() {
+			sch.wait() //waiting all go-routines completed
+			o.closeFlow(out)
+		},
+	})
+}
 
-		wg.Wait() //waiting all go-routines completed
-		o.closeFlow(out)
-	}()
+// call opFunc in a span of the item
+func (tsop transOperater) traceOp(ctx context.Context, o *Observable, x reflect.Value, out *flow) (end bool) {
+	if out.options != nil && out.options.Tracing != nil {
+		var finish func()
+		ctx, finish = o.startSpan(ctx, itemOf(x))
+		defer finish()
+	}
+	return tsop.opFunc(ctx, o, x, out)
 }
 
 func (parent *Observable) TransformOp(tf transformFunc) (o *Observable) {
@@ -79,24 +79,29 @@ func (parent *Observable) TransformOp(tf transformFunc) (o *Observable) {
 	return o
 }
 
-var transformOperater = transOperater{func(ctx context.Context, o *Observable, x reflect.Value, out chan interface{}) (end bool) {
+var transformOperater = transOperater{func(ctx context.Context, o *Observable, x reflect.Value, out *flow) (end bool) {
 	tf := o.flip.(transformFunc)
 	send := func(x interface{}) (endSignal bool) {
 		endSignal = o.sendToFlow(ctx, x, out)
 		return
 	}
-	tf(ctx, x.Interface(), send)
+	defer func() {
+		if e := recover(); e != nil {
+			end = o.sendToFlow(ctx, o.operatorError(ctx, itemOf(x), e), out)
+		}
+	}()
+	tf(ctx, itemOf(x), send)
 	return
 }}
 
-// Map maps each item in Observable by the function with `func(x anytype) anytype` and
+// Map maps each item in Observable by the function with `func(x anytype) anytype` or `func(x anytype) (anytype, error)` and
 // returns a new Observable with applied items.
 func (parent *Observable) Map(f interface{}) (o *Observable) {
 	// check validation of f
 	fv := reflect.ValueOf(f)
 	inType := []reflect.Type{typeAny}
 	outType := []reflect.Type{typeAny}
-	b, ctx_sup := checkFuncUpcast(fv, inType, outType, true)
+	b, ctx_sup, err_sup := checkFuncUpcast(fv, inType, outType, true, true)
 	if !b {
 		panic(ErrFuncFlip)
 	}
@@ -105,18 +110,17 @@ func (parent *Observable) Map(f interface{}) (o *Observable) {
 	o.flip_accept_error = checkFuncAcceptError(fv)
 
 	o.flip_sup_ctx = ctx_sup
+	o.flip_ret_error = err_sup
 	o.flip = fv.Interface()
+	o.flip_fast = fastFuncOf(o.flip)
 	o.operator = mapOperater
 	return o
 }
 
-var mapOperater = transOperater{func(ctx context.Context, o *Observable, x reflect.Value, out chan interface{}) (end bool) {
+var mapOperater = transOperater{func(ctx context.Context, o *Observable, x reflect.Value, out *flow) (end bool) {
 
-	fv := reflect.ValueOf(o.flip)
-	var params = []reflect.Value{x}
-	rs, skip, stop, e := userFuncCall(fv, params)
+	r, skip, stop, e := o.callFlip(ctx, x, out)
 
-	var item interface{} = rs[0].Interface()
 	if stop {
 		end = true
 		return
@@ -124,8 +128,11 @@ var mapOperater = transOperater{func(ctx context.Context, o *Observable, x refle
 	if skip {
 		return
 	}
+	var item interface{}
 	if e != nil {
 		item = e
+	} else {
+		item = r
 	}
 	// send data
 	if !end {
@@ -135,14 +142,15 @@ var mapOperater = transOperater{func(ctx context.Context, o *Observable, x refle
 	return
 }}
 
-// FlatMap maps each item in Observable by the function with `func(x anytype) (o *Observable) ` and
+// FlatMap maps each item in Observable by the function with `func(x anytype) (o *Observable) ` or
+// `func(x anytype) (*Observable, error)` and
 // returns a new Observable with merged observables appling on each items.
 func (parent *Observable) FlatMap(f interface{}) (o *Observable) {
 	// check validation of f
 	fv := reflect.ValueOf(f)
 	inType := []reflect.Type{typeAny}
 	outType := []reflect.Type{typeObservable}
-	b, ctx_sup := checkFuncUpcast(fv, inType, outType, true)
+	b, ctx_sup, err_sup := checkFuncUpcast(fv, inType, outType, true, true)
 	if !b {
 		panic(ErrFuncFlip)
 	}
@@ -151,19 +159,16 @@ func (parent *Observable) FlatMap(f interface{}) (o *Observable) {
 	o.flip_accept_error = checkFuncAcceptError(fv)
 
 	o.flip_sup_ctx = ctx_sup
+	o.flip_ret_error = err_sup
 	o.flip = fv.Interface()
+	o.flip_fast = fastFuncOf(o.flip)
 	o.operator = flatMapOperater
 	return o
 }
 
-var flatMapOperater = transOperater{func(ctx context.Context, o *Observable, x reflect.Value, out chan interface{}) (end bool) {
-
-	fv := reflect.ValueOf(o.flip)
-	var params = []reflect.Value{x}
-	//fmt.Println("x is ", x)
-	rs, skip, stop, e := userFuncCall(fv, params)
+var flatMapOperater = transOperater{func(ctx context.Context, o *Observable, x reflect.Value, out *flow) (end bool) {
 
-	var item = rs[0].Interface().(*Observable)
+	r, skip, stop, e := o.callFlip(ctx, x, out)
 
 	if stop {
 		end = true
@@ -181,33 +186,29 @@ var flatMapOperater = transOperater{func(ctx context.Context, o *Observable, x r
 	}
 	// send data
 	if !end {
-		if item != nil {
-			// subscribe ro without any ObserveOn model
-			ro := item
-			for ; ro.next != nil; ro = ro.next {
-			}
-			ro.connect(ctx)
-
-			ch := ro.outflow
-			for x := range ch {
+		if item, _ := r.(*Observable); item != nil {
+			o.lastInner.Store(item)
+			// subscribe item without any ObserveOn model
+			ch := item.connect(ctx)
+			defer ch.dispose()
+			ch.forEach(ctx, func(x interface{}) bool {
+				_, x = recvItem(ctx, x)
 				end = o.sendToFlow(ctx, x, out)
-				if end {
-					return
-				}
-			}
+				return end
+			})
 		}
 	}
 	return
 }}
 
-// Filter `func(x anytype) bool` filters items in the original Observable and returns
+// Filter `func(x anytype) bool` or `func(x anytype) (bool, error)` filters items in the original Observable and returns
 // a new Observable with the filtered items.
 func (parent *Observable) Filter(f interface{}) (o *Observable) {
 	// check validation of f
 	fv := reflect.ValueOf(f)
 	inType := []reflect.Type{typeAny}
 	outType := []reflect.Type{typeBool}
-	b, ctx_sup := checkFuncUpcast(fv, inType, outType, true)
+	b, ctx_sup, err_sup := checkFuncUpcast(fv, inType, outType, true, true)
 	if !b {
 		panic(ErrFuncFlip)
 	}
@@ -216,18 +217,17 @@ func (parent *Observable) Filter(f interface{}) (o *Observable) {
 	o.flip_accept_error = checkFuncAcceptError(fv)
 
 	o.flip_sup_ctx = ctx_sup
+	o.flip_ret_error = err_sup
 	o.flip = fv.Interface()
+	o.flip_fast = fastFuncOf(o.flip)
 	o.operator = filterOperater
 	return o
 }
 
-var filterOperater = transOperater{func(ctx context.Context, o *Observable, x reflect.Value, out chan interface{}) (end bool) {
+var filterOperater = transOperater{func(ctx context.Context, o *Observable, x reflect.Value, out *flow) (end bool) {
 
-	fv := reflect.ValueOf(o.flip)
-	var params = []reflect.Value{x}
-	rs, skip, stop, e := userFuncCall(fv, params)
+	r, skip, stop, e := o.callFlip(ctx, x, out)
 
-	var item interface{} = rs[0].Interface()
 	if stop {
 		end = true
 		return
@@ -236,25 +236,45 @@ var filterOperater = transOperater{func(ctx context.Context, o *Observable, x re
 		return
 	}
 	if e != nil {
-		item = e
+		end = o.sendToFlow(ctx, e, out)
+		return
 	}
 	// send data
 	if !end {
-		if b, ok := item.(bool); ok && b {
-			end = o.sendToFlow(ctx, x.Interface(), out)
+		if b, ok := r.(bool); ok && b {
+			end = o.sendToFlow(ctx, itemOf(x), out)
 		}
 	}
 
 	return
 }}
 
+// parameters of flip function for item x, or ItemTypeError if x can not be passed by coercion policy
+func (o *Observable) flipParams(ctx context.Context, x reflect.Value) ([]reflect.Value, error) {
+	ft := reflect.TypeOf(o.flip)
+	i := 0
+	if o.flip_sup_ctx {
+		i = 1
+	}
+	t := ft.In(i)
+	op := optionsOf(ctx)
+	xv, ok := coerceValue(x, t, o.stageCoercion(op))
+	if !ok {
+		return nil, &ItemTypeError{Stage: o.stageName(op), Item: itemOf(x), Type: t}
+	}
+	if o.flip_sup_ctx {
+		return []reflect.Value{reflect.ValueOf(ctx), xv}, nil
+	}
+	return []reflect.Value{xv}, nil
+}
+
 func (parent *Observable) newTransformObservable(name string) (o *Observable) {
 	//new Observable
 	o = newObservable()
 	o.Name = name
+	o.kind = name
 
 	//chain Observables
-	parent.next = o
 	o.pred = parent
 	o.root = parent.root
 
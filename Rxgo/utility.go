@@ -5,8 +5,10 @@
 package rxgo
 
 import (
+	"context"
 	"fmt"
 	"reflect"
+	"runtime/debug"
 )
 
 // Test Observer
@@ -28,14 +30,17 @@ func (o InnerObserver) OnCompleted() {
 	fmt.Println(o.name, "Down ")
 }
 
-// func type check, such as `func(x int) bool` satisfied for `func(x anytype) bool`
-func checkFuncUpcast(fv reflect.Value, inType, outType []reflect.Type, ctx_sup bool) (b, ctx_b bool) {
+// func type check, such as `func(x int) bool` satisfied for `func(x anytype) bool`.
+// If err_sup, an extra error result is accepted, such as `func(x int) (bool, error)`
+func checkFuncUpcast(fv reflect.Value, inType, outType []reflect.Type, ctx_sup, err_sup bool) (b, ctx_b, err_b bool) {
 	//fmt.Println(fv.Kind(),reflect.Func)
 	if fv.Kind() != reflect.Func {
 		return // Not func
 	}
 	ft := fv.Type()
-	if ft.NumOut() != len(outType) {
+	if err_sup && ft.NumOut() == len(outType)+1 && ft.Out(len(outType)) == typeError {
+		err_b = true
+	} else if ft.NumOut() != len(outType) {
 		return // Error result parameters
 	}
 	if !ctx_sup {
@@ -69,19 +74,16 @@ func checkFuncUpcast(fv reflect.Value, inType, outType []reflect.Type, ctx_sup b
 			real_t = ft.In(i)
 		}
 
-		//todo: ptr or slice check
+		// items are passed to parameters by the coercion policy of the chain when called, see coerceValue
 		switch {
 		case real_t == t:
 		case t.Kind() == reflect.Interface && real_t.Implements(t):
-		//case ft.In(i).AssignableTo(t):
-		//case ft.In(i).ConvertibleTo(t):
 		default:
 			return
 		}
 	}
 	for i, t := range outType {
 		//fmt.Println(ft.Out(i), t)
-		//todo: ptr or slice check
 		switch {
 		case ft.Out(i) == t:
 		case t.Kind() == reflect.Interface && ft.Out(i).Implements(t):
@@ -93,6 +95,75 @@ func checkFuncUpcast(fv reflect.Value, inType, outType []reflect.Type, ctx_sup b
 	return
 }
 
+// pass x to parameter of type t by the coercion policy
+func coerceValue(x reflect.Value, t reflect.Type, c Coercion) (v reflect.Value, ok bool) {
+	if !x.IsValid() {
+		// nil item
+		switch t.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Slice, reflect.Map, reflect.Chan, reflect.Func:
+			return reflect.Zero(t), true
+		}
+		return
+	}
+	xt := x.Type()
+	switch {
+	case xt == t:
+		return x, true
+	case t.Kind() == reflect.Interface && xt.Implements(t):
+		return x, true
+	case c == CoercionStrict:
+		return
+	case xt.AssignableTo(t):
+		return x, true
+	case c == CoercionConvertible && isNumber(xt) && isNumber(t):
+		v = x.Convert(t)
+		// a number is not truncated, wrapped or overflowed
+		if isNegative(v) != isNegative(x) || !equalNumber(v.Convert(xt), x) {
+			return reflect.Value{}, false
+		}
+		return v, true
+	}
+	return
+}
+
+func isNegative(x reflect.Value) bool {
+	switch x.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return x.Int() < 0
+	case reflect.Float32, reflect.Float64:
+		return x.Float() < 0
+	}
+	return false
+}
+
+// numbers x and y of the same type are equal, NaN equals NaN
+func equalNumber(x, y reflect.Value) bool {
+	switch x.Kind() {
+	case reflect.Float32, reflect.Float64:
+		a, b := x.Float(), y.Float()
+		return a == b || a != a && b != b
+	}
+	return x.Equal(y)
+}
+
+// item of x, nil if x is the zero Value of a nil item
+func itemOf(x reflect.Value) interface{} {
+	if !x.IsValid() {
+		return nil
+	}
+	return x.Interface()
+}
+
+func isNumber(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
 // ckeck gunction the first parameter can accept error
 func checkFuncAcceptError(fv reflect.Value) (b bool) {
 	if fv.Kind() != reflect.Func {
@@ -118,27 +189,56 @@ func checkFuncAcceptError(fv reflect.Value) (b bool) {
 	return
 }
 
-// wrap exception when call user function
-func userFuncCall(fv reflect.Value, params []reflect.Value) (res []reflect.Value, skip, stop bool, eout error) {
+// wrap exception or returned error when call user function of stage o with the item.
+// Results before the returned error are kept with it, and they are nil on panic.
+func userFuncCall(ctx context.Context, o *Observable, item interface{}, fv reflect.Value, params []reflect.Value) (res []reflect.Value, skip, stop bool, eout error) {
 	defer func() {
 		if e := recover(); e != nil {
-			if fe, ok := e.(FlowableError); ok {
-				eout = fe
-				return
-			}
+			skip, stop, eout = o.userPanic(ctx, item, e)
+		}
+	}()
+
+	res = fv.Call(params)
+	if o.flip_ret_error {
+		last := len(res) - 1
+		e, _ := res[last].Interface().(error)
+		res = res[:last]
+		if e != nil {
 			switch e {
 			case ErrSkipItem:
 				skip = true
-				return
 			case ErrEoFlow:
 				stop = true
-				return
 			default:
-				panic(e)
+				eout = e
 			}
 		}
-	}()
+	}
+	return
+}
 
-	res = fv.Call(params)
+// handle a panic of user function of stage o with the item
+func (o *Observable) userPanic(ctx context.Context, item, e interface{}) (skip, stop bool, eout error) {
+	if fe, ok := e.(FlowableError); ok {
+		eout = fe
+		return
+	}
+	switch e {
+	case ErrSkipItem:
+		skip = true
+	case ErrEoFlow:
+		stop = true
+	default:
+		eout = o.operatorError(ctx, item, e)
+	}
 	return
 }
+
+// convert a panic of user function to OperatorError, or re-panic if the chain crashes on panic
+func (o *Observable) operatorError(ctx context.Context, item, v interface{}) *OperatorError {
+	op := optionsOf(ctx)
+	if op != nil && op.CrashOnPanic {
+		panic(v)
+	}
+	return &OperatorError{Stage: o.stageName(op), Item: item, Value: v, Stack: debug.Stack()}
+}
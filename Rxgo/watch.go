@@ -0,0 +1,272 @@
+// Copyright 2018 The SS.SYSU Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rxgo
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// WatchOp is the change of a file
+type WatchOp uint
+
+const (
+	WatchCreate WatchOp = iota + 1
+	WatchModify
+	WatchDelete
+)
+
+func (op WatchOp) String() string {
+	switch op {
+	case WatchCreate:
+		return "create"
+	case WatchModify:
+		return "modify"
+	case WatchDelete:
+		return "delete"
+	}
+	return "unknown"
+}
+
+// WatchEvent is a change of a file under the directory watched by WatchDir
+type WatchEvent struct {
+	Op   WatchOp
+	Path string // path of the file joined with the watched directory
+}
+
+// WatchOptions of WatchDir
+type WatchOptions struct {
+	Patterns     []string      // glob patterns of filepath.Match for base name or relative path of files, all files if empty
+	Debounce     time.Duration // changes are coalesced until no change in this duration
+	PollInterval time.Duration // interval to scan the directory, DefaultWatchPollInterval if not positive
+	Polling      bool          // only scan by PollInterval, without inotify on Linux
+}
+
+// default interval of WatchDir to scan the directory
+var DefaultWatchPollInterval = time.Second
+
+// WatchDir creates an Observable of WatchEvent of files under the directory at path, including subdirectories.
+// Files are scanned every PollInterval, and on Linux also when inotify reports a change.
+// Changes of a file in Debounce are coalesced into one event, such as a created file written many times.
+// It never completes until unsubscription, or an error is sent if the directory can not be scanned.
+func WatchDir(path string, opts WatchOptions) *Observable {
+	o := newGeneratorObservable("WatchDir")
+
+	o.flip = func(ctx context.Context, out *flow) {
+		w := &dirWatcher{root: path, opts: opts}
+		if w.opts.PollInterval <= 0 {
+			w.opts.PollInterval = DefaultWatchPollInterval
+		}
+		w.run(ctx, func(x interface{}) bool {
+			return o.sendToFlow(ctx, x, out)
+		})
+	}
+	o.operator = flipSource
+	return o
+}
+
+// state of a file in a snapshot
+type fileState struct {
+	size int64
+	mod  time.Time
+	mode fs.FileMode
+}
+
+// files and directories under the root
+type dirSnapshot struct {
+	files map[string]fileState // keyed by relative path
+	dirs  []string
+}
+
+// dirNotifier reports that files may change in the added directories. Adding a directory again is allowed
+type dirNotifier interface {
+	events() <-chan struct{}
+	add(dir string)
+	close()
+}
+
+type dirWatcher struct {
+	root     string
+	opts     WatchOptions
+	notifier dirNotifier // nil if only polling
+}
+
+func (w *dirWatcher) run(ctx context.Context, send func(x interface{}) (end bool)) {
+	prev, e := w.scan()
+	if e != nil {
+		send(e)
+		return
+	}
+	if !w.opts.Polling {
+		// polling only if notifier is not supported
+		w.notifier = newDirNotifier()
+	}
+	var notify <-chan struct{}
+	if w.notifier != nil {
+		defer w.notifier.close()
+		notify = w.notifier.events()
+		for _, d := range prev.dirs {
+			w.notifier.add(d)
+		}
+	}
+	ticker := time.NewTicker(w.opts.PollInterval)
+	defer ticker.Stop()
+
+	for again := false; ; {
+		if !again {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-notify:
+			}
+		}
+
+		cur, e := w.scan()
+		if e == nil && w.opts.Debounce > 0 && !prev.equal(cur) {
+			cur, e = w.settle(ctx, notify, cur)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if e != nil {
+			send(e)
+			return
+		}
+		if w.notifier != nil {
+			for _, d := range cur.dirs {
+				w.notifier.add(d)
+			}
+			// files created in new directories before they are watched are found by scanning again
+			again = cur.hasNewDir(prev)
+		}
+		for _, ev := range w.diff(prev, cur) {
+			if end := send(ev); end {
+				return
+			}
+		}
+		prev = cur
+	}
+}
+
+// scan again after debounce until files do not change
+func (w *dirWatcher) settle(ctx context.Context, notify <-chan struct{}, cur *dirSnapshot) (*dirSnapshot, error) {
+	timer := time.NewTimer(w.opts.Debounce)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return cur, nil
+		case <-notify:
+			// changing, wait for debounce again
+			if !timer.Stop() {
+				<-timer.C
+			}
+			timer.Reset(w.opts.Debounce)
+			continue
+		case <-timer.C:
+		}
+		next, e := w.scan()
+		if e != nil || next.equal(cur) {
+			return next, e
+		}
+		cur = next
+		timer.Reset(w.opts.Debounce)
+	}
+}
+
+// scan files under the root
+func (w *dirWatcher) scan() (*dirSnapshot, error) {
+	s := &dirSnapshot{files: make(map[string]fileState)}
+	e := filepath.WalkDir(w.root, func(p string, d fs.DirEntry, e error) error {
+		if e != nil {
+			if p != w.root && os.IsNotExist(e) {
+				return nil // removed when scanning
+			}
+			return e
+		}
+		if d.IsDir() {
+			s.dirs = append(s.dirs, p)
+			return nil
+		}
+		fi, e := d.Info()
+		if e != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(w.root, p)
+		s.files[rel] = fileState{size: fi.Size(), mod: fi.ModTime(), mode: fi.Mode()}
+		return nil
+	})
+	return s, e
+}
+
+func (s *dirSnapshot) equal(other *dirSnapshot) bool {
+	if len(s.files) != len(other.files) {
+		return false
+	}
+	for rel, st := range s.files {
+		if ost, ok := other.files[rel]; !ok || ost != st {
+			return false
+		}
+	}
+	return true
+}
+
+// has any directory not in prev
+func (s *dirSnapshot) hasNewDir(prev *dirSnapshot) bool {
+	dirs := make(map[string]bool, len(prev.dirs))
+	for _, d := range prev.dirs {
+		dirs[d] = true
+	}
+	for _, d := range s.dirs {
+		if !dirs[d] {
+			return true
+		}
+	}
+	return false
+}
+
+// events of files changed from prev to cur in order of path
+func (w *dirWatcher) diff(prev, cur *dirSnapshot) (events []WatchEvent) {
+	for rel, st := range cur.files {
+		if !w.match(rel) {
+			continue
+		}
+		if pst, ok := prev.files[rel]; !ok {
+			events = append(events, WatchEvent{Op: WatchCreate, Path: filepath.Join(w.root, rel)})
+		} else if pst != st {
+			events = append(events, WatchEvent{Op: WatchModify, Path: filepath.Join(w.root, rel)})
+		}
+	}
+	for rel := range prev.files {
+		if _, ok := cur.files[rel]; !ok && w.match(rel) {
+			events = append(events, WatchEvent{Op: WatchDelete, Path: filepath.Join(w.root, rel)})
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Path < events[j].Path
+	})
+	return
+}
+
+// is the file at relative path matched by patterns
+func (w *dirWatcher) match(rel string) bool {
+	if len(w.opts.Patterns) == 0 {
+		return true
+	}
+	for _, p := range w.opts.Patterns {
+		if ok, _ := filepath.Match(p, filepath.Base(rel)); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(p, filepath.ToSlash(rel)); ok {
+			return true
+		}
+	}
+	return false
+}
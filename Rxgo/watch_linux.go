@@ -0,0 +1,68 @@
+// Copyright 2018 The SS.SYSU Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package rxgo
+
+import (
+	"os"
+	"syscall"
+)
+
+const inotifyMask = syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MODIFY | syscall.IN_ATTRIB |
+	syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO
+
+// inotifyNotifier reports changes of watched directories by inotify
+type inotifyNotifier struct {
+	fd   int
+	f    *os.File // pollable file of fd, so that reading it is unblocked by closing
+	c    chan struct{}
+	done chan struct{}
+}
+
+// create inotifyNotifier, or nil if inotify is not available
+func newDirNotifier() dirNotifier {
+	fd, e := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if e != nil {
+		return nil
+	}
+	n := &inotifyNotifier{
+		fd:   fd,
+		f:    os.NewFile(uintptr(fd), "inotify"),
+		c:    make(chan struct{}, 1),
+		done: make(chan struct{}),
+	}
+	go n.read()
+	return n
+}
+
+// read inotify events and report them to c without blocking
+func (n *inotifyNotifier) read() {
+	defer close(n.done)
+	buf := make([]byte, 4096)
+	for {
+		if _, e := n.f.Read(buf); e != nil {
+			return
+		}
+		select {
+		case n.c <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (n *inotifyNotifier) events() <-chan struct{} {
+	return n.c
+}
+
+// add a watch of dir, it is added again if dir is removed and created
+func (n *inotifyNotifier) add(dir string) {
+	syscall.InotifyAddWatch(n.fd, dir, inotifyMask)
+}
+
+func (n *inotifyNotifier) close() {
+	n.f.Close()
+	<-n.done
+}
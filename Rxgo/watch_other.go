@@ -0,0 +1,12 @@
+// Copyright 2018 The SS.SYSU Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+
+package rxgo
+
+// WatchDir only polls on this platform
+func newDirNotifier() dirNotifier {
+	return nil
+}
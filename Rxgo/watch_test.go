@@ -0,0 +1,100 @@
+package rxgo_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pmlpml/rxgo"
+	"github.com/stretchr/testify/assert"
+)
+
+// subscribe WatchDir and receive its events from the channel
+func watchDir(dir string, opts rxgo.WatchOptions) (events chan rxgo.WatchEvent, s *rxgo.Subscription) {
+	events = make(chan rxgo.WatchEvent, 100)
+	if opts.PollInterval == 0 {
+		opts.PollInterval = 10 * time.Millisecond
+	}
+	s = rxgo.WatchDir(dir, opts).SubscribeAsync(func(x rxgo.WatchEvent) {
+		events <- x
+	})
+	// wait for the first scan
+	time.Sleep(30 * time.Millisecond)
+	return
+}
+
+func nextEvent(t *testing.T, events chan rxgo.WatchEvent) rxgo.WatchEvent {
+	select {
+	case ev := <-events:
+		return ev
+	case <-time.After(2 * time.Second):
+		t.Error("no event")
+		return rxgo.WatchEvent{}
+	}
+}
+
+func TestWatchDir(t *testing.T) {
+	for _, polling := range []bool{true, false} {
+		dir := t.TempDir()
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "old.txt"), []byte("old"), 0644), "write error")
+		opts := rxgo.WatchOptions{Polling: polling, Patterns: []string{"*.txt"}}
+		if !polling {
+			// changes are reported by inotify on Linux
+			opts.PollInterval = time.Hour
+			if _, err := os.Stat("/proc/sys/fs/inotify"); err != nil {
+				continue
+			}
+		}
+		events, s := watchDir(dir, opts)
+
+		path := filepath.Join(dir, "sub", "a.txt")
+		assert.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0755), "mkdir error")
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "skip.log"), []byte("skip"), 0644), "write error")
+		assert.NoError(t, os.WriteFile(path, []byte("a"), 0644), "write error")
+		assert.Equal(t, rxgo.WatchEvent{Op: rxgo.WatchCreate, Path: path}, nextEvent(t, events), "create event error")
+
+		assert.NoError(t, os.WriteFile(path, []byte("abc"), 0644), "write error")
+		assert.Equal(t, rxgo.WatchEvent{Op: rxgo.WatchModify, Path: path}, nextEvent(t, events), "modify event error")
+
+		assert.NoError(t, os.Remove(path), "remove error")
+		assert.Equal(t, rxgo.WatchEvent{Op: rxgo.WatchDelete, Path: path}, nextEvent(t, events), "delete event error")
+		s.Dispose()
+	}
+}
+
+func TestWatchDirDebounce(t *testing.T) {
+	dir := t.TempDir()
+	events, s := watchDir(dir, rxgo.WatchOptions{Debounce: 100 * time.Millisecond})
+	defer s.Dispose()
+
+	path := filepath.Join(dir, "a.txt")
+	f, err := os.Create(path)
+	assert.NoError(t, err, "create error")
+	for i := 0; i < 5; i++ {
+		f.WriteString("line\n")
+		time.Sleep(10 * time.Millisecond)
+	}
+	f.Close()
+	tmp := filepath.Join(dir, "tmp.txt")
+	os.WriteFile(tmp, []byte("tmp"), 0644)
+	os.Remove(tmp)
+
+	assert.Equal(t, rxgo.WatchEvent{Op: rxgo.WatchCreate, Path: path}, nextEvent(t, events), "coalesced event error")
+	select {
+	case ev := <-events:
+		t.Errorf("unexpected event %v", ev)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestWatchDirNotExist(t *testing.T) {
+	var errs []error
+	rxgo.WatchDir(filepath.Join(t.TempDir(), "none"), rxgo.WatchOptions{}).Subscribe(rxgo.ObserverMonitor{
+		Error: func(e error) {
+			errs = append(errs, e)
+		},
+	})
+	assert.Equal(t, 1, len(errs), "error count error")
+	assert.True(t, os.IsNotExist(errs[0]), "not exist error")
+}